@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"ethglobal/pkg/config"
+	"ethglobal/pkg/controllers"
+	"ethglobal/pkg/git"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type helper struct {
+	controller   controllers.Controller
+	repository   string
+	gitDirectory string
+	reader       *bufio.Reader
+	writer       *bufio.Writer
+}
+
+func (h *helper) readLine() (string, error) {
+	line, err := h.reader.ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+func (h *helper) batch(first string, prefix string) ([]string, error) {
+	lines := []string{strings.TrimPrefix(first, prefix)}
+	for {
+		line, err := h.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			return lines, nil
+		}
+		if !strings.HasPrefix(line, prefix) {
+			return nil, fmt.Errorf("unexpected command %q in batch", line)
+		}
+		lines = append(lines, strings.TrimPrefix(line, prefix))
+	}
+}
+
+func (h *helper) list() error {
+	refs, head, err := h.controller.RemoteRefs(h.repository)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		if ref.Name != "HEAD" {
+			_, _ = fmt.Fprintf(h.writer, "%s %s\n", ref.Oid, ref.Name)
+		}
+	}
+	if head != "" {
+		_, _ = fmt.Fprintf(h.writer, "@%s HEAD\n", head)
+	}
+	_, _ = fmt.Fprintln(h.writer)
+	return nil
+}
+
+func (h *helper) fetch(line string) error {
+	_, err := h.batch(line, "fetch ")
+	if err != nil {
+		return err
+	}
+
+	err = h.controller.FetchRemote(h.repository, h.gitDirectory)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(h.writer)
+	return nil
+}
+
+func (h *helper) push(line string) error {
+	refspecs, err := h.batch(line, "push ")
+	if err != nil {
+		return err
+	}
+
+	updates := make([]git.RefUpdate, 0, len(refspecs))
+	for _, refspec := range refspecs {
+		update, err := git.ParseRefspec(refspec)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, update)
+	}
+
+	err = h.controller.PushRemote(h.repository, h.gitDirectory, updates)
+	for _, update := range updates {
+		switch {
+		case update.Error != "":
+			_, _ = fmt.Fprintf(h.writer, "error %s %s\n", update.Destination, update.Error)
+		case err != nil:
+			_, _ = fmt.Fprintf(h.writer, "error %s %s\n", update.Destination, strings.ReplaceAll(err.Error(), "\n", " "))
+		default:
+			_, _ = fmt.Fprintf(h.writer, "ok %s\n", update.Destination)
+		}
+	}
+	_, _ = fmt.Fprintln(h.writer)
+	return nil
+}
+
+func (h *helper) serve() error {
+	for {
+		line, err := h.readLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case line == "":
+			return nil
+		case line == "capabilities":
+			_, _ = fmt.Fprint(h.writer, "fetch\npush\n\n")
+		case line == "list" || line == "list for-push":
+			err = h.list()
+		case strings.HasPrefix(line, "fetch "):
+			err = h.fetch(line)
+		case strings.HasPrefix(line, "push "):
+			err = h.push(line)
+		default:
+			err = fmt.Errorf("unsupported command %q", line)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = h.writer.Flush()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func main() {
+	log.SetPrefix("git-remote-ccg: ")
+	log.SetFlags(0)
+
+	if len(os.Args) < 3 {
+		log.Fatal("usage: git-remote-ccg <remote> <repository identifier>")
+	}
+
+	gitDirectory, err := filepath.Abs(os.Getenv("GIT_DIR"))
+	if err != nil || os.Getenv("GIT_DIR") == "" {
+		log.Fatal("GIT_DIR is not set, git-remote-ccg must be run by git")
+	}
+	_ = os.Unsetenv("GIT_DIR")
+
+	repository := strings.TrimPrefix(os.Args[2], "ccg://")
+	if repository == "" {
+		log.Fatal("missing repository identifier")
+	}
+
+	configuration := config.LoadConfig()
+	controller, _, err := controllers.InitController(configuration)
+	if err != nil {
+		log.Fatalf("error initialising controller: %v", err)
+	}
+
+	h := &helper{
+		controller:   controller,
+		repository:   repository,
+		gitDirectory: gitDirectory,
+		reader:       bufio.NewReader(os.Stdin),
+		writer:       bufio.NewWriter(os.Stdout),
+	}
+
+	err = h.serve()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -4,30 +4,25 @@ import (
 	"encoding/json"
 	"errors"
 	"ethglobal/pkg/config"
-	"ethglobal/pkg/contract"
 	"ethglobal/pkg/controllers"
-	"ethglobal/pkg/lighthouse"
+	"ethglobal/pkg/git"
 	"ethglobal/pkg/types"
 	"ethglobal/pkg/utils"
 	"fmt"
 	"github.com/spf13/cobra"
 	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 func main() {
 	configuration := config.LoadConfig()
 
-	actions, rootCtx, err := contract.InitContractActions(&configuration)
+	controller, rootCtx, err := controllers.InitController(configuration)
 	if err != nil {
-		return
-	}
-
-	lighthouseClient := lighthouse.InitLightHouseClient(configuration)
-
-	controller := controllers.Controller{
-		ActionContracts:    actions,
-		Lighthouse:         lighthouseClient,
-		EncryptionKeyBytes: []byte(configuration.EncryptionKey),
+		log.Fatalf("error initialising controller: %v", err)
 	}
 
 	var address = &cobra.Command{
@@ -42,36 +37,60 @@ func main() {
 		},
 	}
 
+	var pushOptions types.PushOptions
+	var pushRepository string
 	var push = &cobra.Command{
 		Use:   "push",
-		Short: "push [repository identifier] [path/to/.git.zip] [latest commit] -> Transaction Id",
-		Long:  "Push the latest git history and metadata on cold storage on Lighthouse, prints Transaction ID",
+		Short: "push [repository identifier] [path/to/archive.bundle or path/to/repository] [latest commit] -> Transaction Id",
+		Long:  "Push the latest git history and metadata on cold storage on Lighthouse, prints Transaction ID. With --repo the archive is built from the repository and the commit is read from its HEAD",
 		RunE: func(_ *cobra.Command, args []string) error {
-			if len(args) != 3 {
-				return errors.New(fmt.Sprintf("expected 3 arguments , got %d", len(args)))
+			if pushOptions.Format != git.FormatBundle && pushOptions.Format != git.FormatTarball {
+				return fmt.Errorf("unknown archive format %q, expected %s or %s", pushOptions.Format, git.FormatBundle, git.FormatTarball)
 			}
 
-			transactionId, err := controller.PushColdStorage(args[0], args[1], args[2])
+			var transactionId string
+			var err error
+			if pushRepository != "" {
+				if len(args) != 1 {
+					return errors.New(fmt.Sprintf("expected 1 arguments with --repo, got %d", len(args)))
+				}
+				transactionId, err = controller.PushRepository(args[0], pushRepository, pushOptions)
+			} else {
+				if len(args) != 3 {
+					return errors.New(fmt.Sprintf("expected 3 arguments , got %d", len(args)))
+				}
+				transactionId, err = controller.PushColdStorage(args[0], args[1], args[2], pushOptions)
+			}
 			if err != nil {
 				return err
 			}
 
 			(*rootCtx).Done()
-			log.Printf(transactionId)
+			if transactionId != "" {
+				log.Print(transactionId)
+			}
 			return nil
 		},
 	}
 
+	push.Flags().StringVar(&pushRepository, "repo", "", "build the archive from this repository instead of taking one")
+	push.Flags().BoolVar(&pushOptions.Release, "release", false, "keep this version pinned when superseded versions are unpinned")
+	push.Flags().BoolVar(&pushOptions.Force, "force", false, "push even if the archive is unchanged since the latest version")
+	push.Flags().StringSliceVar(&pushOptions.ReleaseTags, "release-tags", nil, "only archive a repository directory when a new tag matches one of these patterns")
+	push.Flags().BoolVar(&pushOptions.Full, "full", false, "bundle the whole repository instead of only what changed since the latest version")
+	push.Flags().StringVar(&pushOptions.Format, "format", git.FormatBundle, "archive format to build from a repository directory, bundle or tar.gz")
+
+	var selector types.VersionSelector
 	var pull = &cobra.Command{
 		Use:   "pull",
-		Short: "pull [repository identifier] [path/to/output.git.zip] -> Metadata",
-		Long:  "Pull the latest git history from cold storage on Lighthouse, prints Metadata",
+		Short: "pull [repository identifier] [path/to/output.bundle] -> Metadata",
+		Long:  "Pull the latest git history, or the version picked with --version or --commit, from cold storage on Lighthouse, prints Metadata",
 		RunE: func(_ *cobra.Command, args []string) error {
 			if len(args) != 2 {
 				return errors.New(fmt.Sprintf("expected 2 arguments, got %d", len(args)))
 			}
 
-			bytes, err := controller.RetrieveColdStorage(args[0], args[1])
+			bytes, err := controller.RetrieveColdStorage(args[0], args[1], selector)
 			if err != nil {
 				return err
 			}
@@ -83,20 +102,176 @@ func main() {
 			}
 
 			(*rootCtx).Done()
-			log.Printf(string(bytes))
+			log.Print(string(bytes))
+			return nil
+		},
+	}
+
+	var restore = &cobra.Command{
+		Use:   "restore",
+		Short: "restore [repository identifier] [path/to/repository] -> Metadata",
+		Long:  "Pull the latest archive, or the version picked with --version or --commit, from cold storage, verify it and restore it into a git repository, prints Metadata",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New(fmt.Sprintf("expected 2 arguments, got %d", len(args)))
+			}
+
+			bytes, err := controller.RestoreRepository(args[0], args[1], selector)
+			if err != nil {
+				return err
+			}
+
+			(*rootCtx).Done()
+			log.Print(string(bytes))
+			return nil
+		},
+	}
+
+	for _, command := range []*cobra.Command{pull, restore} {
+		command.Flags().Uint32Var(&selector.Version, "version", 0, "restore this version instead of the latest")
+		command.Flags().StringVar(&selector.Commit, "commit", "", "restore the latest version archived at this commit")
+		command.MarkFlagsMutuallyExclusive("version", "commit")
+	}
+
+	var historyJson bool
+	var history = &cobra.Command{
+		Use:   "log",
+		Short: "log [repository identifier] -> Version History",
+		Long:  "List archived versions of a repository, newest first, with their commit, refs, size, CID and transaction",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New(fmt.Sprintf("expected 1 arguments, got %d", len(args)))
+			}
+
+			entries, err := controller.History(args[0])
+			if err != nil {
+				return err
+			}
+
+			(*rootCtx).Done()
+			if historyJson {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(entries)
+			}
+
+			orDash := func(value string) string {
+				if value == "" {
+					return "-"
+				}
+				return value
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			for _, entry := range entries {
+				var refs []string
+				for _, ref := range git.RefList(entry.Refs) {
+					if ref.Name != "HEAD" {
+						refs = append(refs, strings.TrimPrefix(strings.TrimPrefix(ref.Name, "refs/heads/"), "refs/"))
+					}
+				}
+
+				var size, block, timestamp string
+				if entry.Size != 0 {
+					size = fmt.Sprintf("%d bytes", entry.Size)
+				}
+				if entry.Block != 0 {
+					block = fmt.Sprintf("block %d", entry.Block)
+				}
+				if entry.Timestamp != 0 {
+					timestamp = time.Unix(entry.Timestamp, 0).UTC().Format(time.RFC3339)
+				}
+
+				label := fmt.Sprintf("v%d", entry.Version)
+				if entry.Release {
+					label += " release"
+				}
+
+				_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					label, orDash(entry.CommitHash), orDash(strings.Join(refs, ",")), orDash(size),
+					orDash(entry.Cid), orDash(entry.Transaction), orDash(block), orDash(timestamp))
+			}
+			return writer.Flush()
+		},
+	}
+
+	history.Flags().BoolVar(&historyJson, "json", false, "print the history as JSON")
+
+	var hookRoot string
+	var hook = &cobra.Command{
+		Use:   "hook",
+		Short: "hook [receive|upload] [repository]",
+		Long:  "Serve git pushes and fetches over SSH, archiving pushed repositories and restoring fetched ones from cold storage",
+	}
+
+	var hookReceive = &cobra.Command{
+		Use:          "receive",
+		Short:        "receive [repository]",
+		Long:         "Run git receive-pack for the repository, then push it to cold storage",
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New(fmt.Sprintf("expected 1 arguments, got %d", len(args)))
+			}
+
+			err := controller.ReceiveHook(hookRoot, args[0], os.Stdin, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+
+			(*rootCtx).Done()
+			return nil
+		},
+	}
+
+	var hookUpload = &cobra.Command{
+		Use:          "upload",
+		Short:        "upload [repository]",
+		Long:         "Restore the repository from cold storage, then run git upload-pack for it",
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New(fmt.Sprintf("expected 1 arguments, got %d", len(args)))
+			}
+
+			err := controller.UploadHook(hookRoot, args[0], os.Stdin, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+
+			(*rootCtx).Done()
 			return nil
 		},
 	}
 
+	hook.PersistentFlags().StringVar(&hookRoot, "root", "/home/git", "directory holding the served repositories")
+	hook.AddCommand(hookReceive)
+	hook.AddCommand(hookUpload)
+
+	var metadataRef string
 	var metadata = &cobra.Command{
 		Use:   "metadata",
 		Short: "metadata [repository identifier] -> Metadata",
-		Long:  "Get metadata of a repository from Lighthouse",
+		Long:  "Get metadata of a repository from Lighthouse, or with --ref list the versions containing a branch or tag",
 		RunE: func(_ *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return errors.New(fmt.Sprintf("expected 1 arguments, got %d", len(args)))
 			}
 
+			if metadataRef != "" {
+				matches, err := controller.FindRef(args[0], metadataRef)
+				if err != nil {
+					return err
+				}
+
+				(*rootCtx).Done()
+				writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				for _, match := range matches {
+					_, _ = fmt.Fprintf(writer, "v%d\t%s\t%s\n", match.Version, match.Ref, match.Commit)
+				}
+				return writer.Flush()
+			}
+
 			bytes, err := controller.RetrieveLatestMetaData(args[0])
 			if err != nil {
 				return err
@@ -109,19 +284,203 @@ func main() {
 			}
 
 			(*rootCtx).Done()
-			log.Printf(string(bytes))
+			log.Print(string(bytes))
 			return nil
 		},
 	}
 
+	metadata.Flags().StringVar(&metadataRef, "ref", "", "list the versions containing this branch or tag")
+
+	var deals = &cobra.Command{
+		Use:   "deals",
+		Short: "deals [repository identifier] -> Filecoin Deals",
+		Long:  "Report the Filecoin storage deals Lighthouse has made for the latest archive of a repository",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New(fmt.Sprintf("expected 1 arguments, got %d", len(args)))
+			}
+
+			results, err := controller.RetrieveDeals(args[0])
+			if err != nil {
+				return err
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			for _, result := range results {
+				_, _ = fmt.Fprintf(writer, "%s\t%s\treplication %d\n", result.Label, result.Cid, result.Replication())
+				if result.Error != "" {
+					_, _ = fmt.Fprintf(writer, "\terror: %s\n", result.Error)
+					continue
+				}
+				if len(result.Deals) == 0 {
+					_, _ = fmt.Fprintf(writer, "\tno deals yet\n")
+					continue
+				}
+
+				for _, deal := range result.Deals {
+					expiry := "-"
+					if deal.EndEpoch != 0 {
+						expiry = types.EpochTime(configuration.Chain, int64(deal.EndEpoch)).Format(time.RFC3339)
+					}
+					_, _ = fmt.Fprintf(writer, "\tdeal %d\t%s\t%s\texpires %s\n", deal.Id(), deal.Provider(), deal.DealStatus, expiry)
+				}
+			}
+
+			(*rootCtx).Done()
+			return writer.Flush()
+		},
+	}
+
+	var prove = &cobra.Command{
+		Use:   "prove",
+		Short: "prove [repository identifier] -> Transaction Id",
+		Long:  "Verify the PoDSI inclusion proofs of the latest archive against their Filecoin aggregates and record them in the metadata",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New(fmt.Sprintf("expected 1 arguments, got %d", len(args)))
+			}
+
+			results, transactionId, err := controller.ProveInclusion(args[0])
+			if err != nil {
+				return err
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			for _, result := range results {
+				if result.Error != "" {
+					_, _ = fmt.Fprintf(writer, "%s\t%s\tunverified\n\terror: %s\n", result.Label, result.Cid, result.Error)
+					continue
+				}
+
+				record := result.Record
+				_, _ = fmt.Fprintf(writer, "%s\t%s\tverified\n", result.Label, result.Cid)
+				_, _ = fmt.Fprintf(writer, "\tpiece %s\t%d bytes\n", record.PieceCid, record.PieceSize)
+				_, _ = fmt.Fprintf(writer, "\taggregate %s\t%d bytes\n", record.AggregateCid, record.AggregateSize)
+				for _, deal := range record.Deals {
+					_, _ = fmt.Fprintf(writer, "\tdeal %d\t%s\n", deal.DealId, deal.Provider)
+				}
+			}
+
+			err = writer.Flush()
+			if err != nil {
+				return err
+			}
+
+			(*rootCtx).Done()
+			if transactionId != "" {
+				log.Print(transactionId)
+			}
+			return nil
+		},
+	}
+
+	printPins := func(statuses []types.PinStatus) error {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, status := range statuses {
+			state := "unpinned"
+			if status.Pinned {
+				state = "pinned"
+			}
+			if status.Error != "" {
+				state = "error: " + status.Error
+			}
+
+			retention := "superseded"
+			if status.Keep {
+				retention = "kept"
+			}
+
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", status.Label, retention, status.Backend, status.Id, state)
+		}
+		return writer.Flush()
+	}
+
+	var pinVersion uint32
+	var pins = &cobra.Command{
+		Use:   "pins",
+		Short: "pins list|unpin|repin [repository identifier]",
+		Long:  "Manage what the archived versions of a repository keep pinned in storage",
+	}
+
+	var pinsList = &cobra.Command{
+		Use:   "list",
+		Short: "list [repository identifier] -> Pins",
+		Long:  "List the archives, chunks and metadata recorded for a repository and whether they are still pinned",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New(fmt.Sprintf("expected 1 arguments, got %d", len(args)))
+			}
+
+			statuses, err := controller.ListPins(args[0])
+			if err != nil {
+				return err
+			}
+
+			(*rootCtx).Done()
+			return printPins(statuses)
+		},
+	}
+
+	var pinsUnpin = &cobra.Command{
+		Use:   "unpin",
+		Short: "unpin [repository identifier] -> Pins",
+		Long:  "Unpin superseded metadata and archives of versions that are neither the latest, a release nor a base either builds on",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New(fmt.Sprintf("expected 1 arguments, got %d", len(args)))
+			}
+
+			statuses, err := controller.Unpin(args[0], pinVersion)
+			if err != nil {
+				return err
+			}
+
+			(*rootCtx).Done()
+			return printPins(statuses)
+		},
+	}
+
+	var pinsRepin = &cobra.Command{
+		Use:   "repin",
+		Short: "repin [repository identifier] -> Pins",
+		Long:  "Pin everything recorded for a repository again, re-uploading content a backend no longer holds",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New(fmt.Sprintf("expected 1 arguments, got %d", len(args)))
+			}
+
+			statuses, err := controller.Repin(args[0], pinVersion)
+			if err != nil {
+				return err
+			}
+
+			(*rootCtx).Done()
+			return printPins(statuses)
+		},
+	}
+
+	pinsUnpin.Flags().Uint32Var(&pinVersion, "version", 0, "only unpin this version")
+	pinsRepin.Flags().Uint32Var(&pinVersion, "version", 0, "only repin this version")
+	pins.AddCommand(pinsList)
+	pins.AddCommand(pinsUnpin)
+	pins.AddCommand(pinsRepin)
+
 	var root = &cobra.Command{
 		Use: "ccg",
 	}
 
 	root.AddCommand(push)
 	root.AddCommand(pull)
+	root.AddCommand(restore)
 	root.AddCommand(address)
 	root.AddCommand(metadata)
+	root.AddCommand(history)
+	root.AddCommand(deals)
+	root.AddCommand(prove)
+	root.AddCommand(pins)
+	root.AddCommand(hook)
 
-	_ = root.Execute()
+	if root.Execute() != nil {
+		os.Exit(1)
+	}
 }
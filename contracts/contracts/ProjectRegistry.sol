@@ -9,8 +9,11 @@ contract ProjectRegistry {
 
     mapping (bytes32 => Project) private projects;
 
+    event ProjectUpdated(bytes32 indexed index, bytes cid, bytes metaData);
+
     function setProject(bytes32 index, bytes memory cid, bytes memory metaData) public {
         projects[index] = Project(cid, metaData);
+        emit ProjectUpdated(index, cid, metaData);
     }
 
     function getProject(bytes32 index) public view returns (bytes memory, bytes memory, bool) {
@@ -0,0 +1,132 @@
+package car
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"ethglobal/pkg/unixfs"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+type Writer struct {
+	output  io.WriteSeeker
+	builder *unixfs.Builder
+	header  int
+	seen    map[cid.Cid]bool
+	size    int64
+	root    cid.Cid
+}
+
+func header(root cid.Cid) []byte {
+	rootBytes := append([]byte{0}, root.Bytes()...)
+
+	body := []byte{0xa2}
+	body = append(body, 0x65)
+	body = append(body, "roots"...)
+	body = append(body, 0x81, 0xd8, 0x2a)
+	if len(rootBytes) < 24 {
+		body = append(body, 0x40|byte(len(rootBytes)))
+	} else {
+		body = append(body, 0x58, byte(len(rootBytes)))
+	}
+	body = append(body, rootBytes...)
+	body = append(body, 0x67)
+	body = append(body, "version"...)
+	body = append(body, 0x01)
+
+	return append(binary.AppendUvarint(nil, uint64(len(body))), body...)
+}
+
+func placeholder() (cid.Cid, error) {
+	sum := sha256.Sum256(nil)
+	hash, err := multihash.Encode(sum[:], multihash.SHA2_256)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return cid.NewCidV1(cid.DagProtobuf, hash), nil
+}
+
+func NewWriter(output io.WriteSeeker) (*Writer, error) {
+	root, err := placeholder()
+	if err != nil {
+		return nil, err
+	}
+
+	placeholderHeader := header(root)
+	_, err = output.Write(placeholderHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &Writer{
+		output: output,
+		header: len(placeholderHeader),
+		seen:   map[cid.Cid]bool{},
+	}
+	writer.builder = unixfs.NewBuilder(1, true)
+	writer.builder.OnBlock = writer.block
+	return writer, nil
+}
+
+func (w *Writer) block(c cid.Cid, data []byte) error {
+	if w.seen[c] {
+		return nil
+	}
+	w.seen[c] = true
+
+	cidBytes := c.Bytes()
+	section := binary.AppendUvarint(nil, uint64(len(cidBytes)+len(data)))
+	section = append(section, cidBytes...)
+	_, err := w.output.Write(append(section, data...))
+	return err
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.root.Defined() {
+		return 0, errors.New("write to finished car writer")
+	}
+
+	n, err := w.builder.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *Writer) Size() int64 {
+	return w.size
+}
+
+func (w *Writer) Finish() (cid.Cid, error) {
+	if w.root.Defined() {
+		return w.root, nil
+	}
+
+	root, err := w.builder.Sum()
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	rootHeader := header(root)
+	if len(rootHeader) != w.header {
+		return cid.Undef, fmt.Errorf("unexpected root cid %s", root)
+	}
+
+	_, err = w.output.Seek(0, io.SeekStart)
+	if err != nil {
+		return cid.Undef, err
+	}
+	_, err = w.output.Write(rootHeader)
+	if err != nil {
+		return cid.Undef, err
+	}
+	_, err = w.output.Seek(0, io.SeekEnd)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	w.root = root
+	return root, nil
+}
@@ -1,12 +1,17 @@
 package config
 
 import (
+	"errors"
 	"ethglobal/pkg/types"
 	"github.com/joho/godotenv"
+	"io/fs"
 	"log"
 	"math/big"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -20,16 +25,91 @@ func readInt(variable string, address *int) {
 	*address = temp
 }
 
+func readOptionalInt(variable string, fallback int, address *int) {
+	if os.Getenv(variable) == "" {
+		*address = fallback
+		return
+	}
+
+	readInt(variable, address)
+}
+
+func readBool(variable string, address *bool) {
+	value := os.Getenv(variable)
+	if value == "" {
+		*address = false
+		return
+	}
+
+	temp, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("error converting %v to boolean", variable)
+	}
+
+	*address = temp
+}
+
+func readOptionalString(variable string, fallback string, address *string) {
+	readString(variable, address)
+	if *address == "" {
+		*address = fallback
+	}
+}
+
+func readList(variable string, address *[]string) {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(variable), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	*address = list
+}
+
 func readString(variable string, address *string) {
 	var temp string
 	temp = os.Getenv(variable)
 	*address = temp
 }
 
+func readGateways(variable string, fallback time.Duration, address *[]types.Gateway) {
+	var gateways []types.Gateway
+	for _, entry := range strings.Split(os.Getenv(variable), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		gateway := types.Gateway{Url: entry, Timeout: fallback}
+		if index := strings.LastIndex(entry, "|"); index >= 0 {
+			timeout, err := time.ParseDuration(entry[index+1:])
+			if err != nil {
+				log.Fatalf("error converting timeout of gateway %v in %v", entry, variable)
+			}
+			gateway = types.Gateway{Url: entry[:index], Timeout: timeout}
+		}
+
+		parsed, err := url.Parse(gateway.Url)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			log.Fatalf("invalid gateway url %v in %v", gateway.Url, variable)
+		}
+
+		gateways = append(gateways, gateway)
+	}
+
+	*address = gateways
+}
+
 func LoadConfig() types.Configuration {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("error loading .env file")
+	var files []string
+	if path := os.Getenv("CCG_ENV"); path != "" {
+		files = append(files, path)
+	}
+
+	err := godotenv.Load(files...)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("error loading .env file: %v", err)
 	}
 
 	var configuration types.Configuration
@@ -42,6 +122,8 @@ func LoadConfig() types.Configuration {
 	configuration.SetMinutes = time.Minute * time.Duration(minutes)
 
 	readString("LIGHTHOUSE_KEY", &configuration.LighthouseKey)
+	readOptionalString("LIGHTHOUSE_API_URL", "https://api.lighthouse.storage", &configuration.LighthouseApiUrl)
+	readOptionalString("LIGHTHOUSE_UPLOAD_URL", "https://upload.lighthouse.storage", &configuration.LighthouseUploadUrl)
 	readInt("CONNECTION_TIMEOUT_SECONDS", &seconds)
 	configuration.ConnectionTimeout = time.Second * time.Duration(seconds)
 
@@ -56,5 +138,37 @@ func LoadConfig() types.Configuration {
 
 	readString("ENCRYPTION_KEY", &configuration.EncryptionKey)
 
+	readString("STORAGE_BACKEND", &configuration.StorageBackend)
+	readOptionalInt("STORAGE_MIN_REPLICAS", 0, &configuration.StorageMinReplicas)
+	readBool("STORAGE_RACE", &configuration.StorageRace)
+	readString("LOCAL_STORAGE_DIRECTORY", &configuration.LocalStorageDirectory)
+	readString("KUBO_API_URL", &configuration.KuboApiUrl)
+	readString("KUBO_AUTHORIZATION", &configuration.KuboAuthorization)
+	readGateways("IPFS_GATEWAYS", configuration.ConnectionTimeout, &configuration.Gateways)
+	readBool("GATEWAY_RACE", &configuration.GatewayRace)
+	readString("S3_ENDPOINT", &configuration.S3Endpoint)
+	readString("S3_BUCKET", &configuration.S3Bucket)
+	readString("S3_REGION", &configuration.S3Region)
+	readString("S3_PREFIX", &configuration.S3Prefix)
+	readString("S3_ACCESS_KEY", &configuration.S3AccessKey)
+	readString("S3_SECRET_KEY", &configuration.S3SecretKey)
+
+	var chunkMegabytes int
+	readOptionalInt("CHUNK_SIZE_MEGABYTES", 0, &chunkMegabytes)
+	configuration.ChunkSize = int64(chunkMegabytes) * 1024 * 1024
+	readOptionalInt("CHUNK_RETRIES", 3, &configuration.ChunkRetries)
+	readString("JOURNAL_DIRECTORY", &configuration.JournalDirectory)
+	if configuration.JournalDirectory == "" {
+		configuration.JournalDirectory = filepath.Join(configuration.KeystoreDirectory, "journal")
+	}
+	readOptionalString("LEDGER_DIRECTORY", filepath.Join(configuration.KeystoreDirectory, "ledger"), &configuration.LedgerDirectory)
+
+	readBool("CAR_UPLOADS", &configuration.CarUploads)
+	readString("CAR_DIRECTORY", &configuration.CarDirectory)
+	readList("RELEASE_TAGS", &configuration.ReleaseTags)
+
+	readBool("REPRODUCIBLE_ARCHIVES", &configuration.ReproducibleArchives)
+	readBool("CONVERGENT_ENCRYPTION", &configuration.ConvergentEncryption)
+
 	return configuration
 }
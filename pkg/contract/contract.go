@@ -1,6 +1,7 @@
 package contract
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"ethglobal/pkg/abi"
@@ -49,6 +50,10 @@ func InitContractActions(configuration *types.Configuration) (*types.ContractAct
 		return nil, nil, err
 	}
 
+	if configuration.ContactAddress == "" {
+		return nil, nil, errors.New("CONTRACT_ADDRESS is not set, deploy contracts/ProjectRegistry.sol and set it to the address")
+	}
+
 	contractAddress := common.HexToAddress(configuration.ContactAddress)
 	bytecode, err := client.CodeAt(context.Background(), contractAddress, nil)
 	if err != nil {
@@ -63,9 +68,17 @@ func InitContractActions(configuration *types.Configuration) (*types.ContractAct
 		return nil, nil, err
 	}
 
+	parsed, err := abi.AbiMetaData.GetAbi()
+	if err != nil {
+		return nil, nil, err
+	}
+	updated := parsed.Events["ProjectUpdated"].ID
+
 	return &types.ContractActions{
 		Chain:       configuration.Chain,
 		Contract:    contract,
+		Client:      client,
+		Updates:     bytes.Contains(bytecode, updated.Bytes()),
 		Account:     account,
 		Keystore:    ks,
 		RootContext: ctx,
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"errors"
+	"ethglobal/pkg/git"
+	"ethglobal/pkg/types"
+	"ethglobal/pkg/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func chain(versions []types.VersionMetaData, version types.VersionMetaData) ([]types.VersionMetaData, error) {
+	chain := []types.VersionMetaData{version}
+	for chain[0].Parent != 0 {
+		parent := chain[0].Parent
+		if parent >= chain[0].Version || int(parent) > len(versions) {
+			return nil, fmt.Errorf("version %d builds on unknown version %d", chain[0].Version, parent)
+		}
+		chain = append([]types.VersionMetaData{versions[parent-1]}, chain...)
+	}
+	return chain, nil
+}
+
+func exclusions(versions []types.VersionMetaData, options types.PushOptions) []string {
+	if options.Full || len(versions) == 0 {
+		return nil
+	}
+
+	latest := versions[len(versions)-1]
+	if latest.Format != git.FormatBundle {
+		return nil
+	}
+
+	var exclude []string
+	for _, ref := range git.RefList(latest.Refs) {
+		exclude = append(exclude, ref.Oid)
+	}
+	return exclude
+}
+
+func describeRepository(repositoryPath string, next *types.VersionMetaData) error {
+	refs, err := git.Refs(repositoryPath)
+	if err != nil {
+		return err
+	}
+
+	next.Peeled, err = git.Peeled(repositoryPath)
+	if err != nil {
+		return err
+	}
+
+	next.Refs = git.RefMap(refs)
+	next.Head = git.SymbolicHead(repositoryPath)
+	return nil
+}
+
+func describeBundle(archivePath string, versions []types.VersionMetaData, next *types.VersionMetaData) error {
+	header, err := git.ReadBundleHeader(archivePath)
+	if err != nil {
+		return err
+	}
+
+	if len(header.Prerequisites) == 0 {
+		if next.Refs == nil {
+			next.Refs = git.RefMap(header.Refs)
+		}
+	} else {
+		if len(versions) == 0 {
+			return errors.New("incremental bundle has no previous version to build on")
+		}
+
+		latest := versions[len(versions)-1]
+		next.Parent = latest.Version
+		if next.Refs == nil {
+			refs := map[string]string{}
+			for name, oid := range latest.Refs {
+				refs[name] = oid
+			}
+			for _, ref := range header.Refs {
+				refs[ref.Name] = ref.Oid
+			}
+			next.Refs = refs
+		}
+	}
+
+	if next.Head == "" {
+		next.Head = git.Head(git.RefList(next.Refs))
+	}
+
+	next.Fingerprint = git.RefsFingerprint(git.RefList(next.Refs))
+	return nil
+}
+
+func (c Controller) downloadVersion(version types.VersionMetaData, locations []types.StorageLocation, output string) error {
+	var err error
+	if version.Chunked {
+		err = c.downloadChunked(locations, output)
+	} else {
+		err = c.downloadStream(locations, output)
+	}
+	if err != nil || version.ArchiveSha256 == "" {
+		return err
+	}
+
+	digest, _, err := utils.FileSHA256(output)
+	if err != nil {
+		return err
+	}
+
+	if digest != version.ArchiveSha256 {
+		return fmt.Errorf("%w: version %d archive has sha256 %s, expected %s", git.ErrIntegrity, version.Version, digest, version.ArchiveSha256)
+	}
+	return nil
+}
+
+func verifyRestore(target string, version *types.VersionMetaData) error {
+	if version == nil {
+		return git.Verify(target, nil, "", "")
+	}
+	return git.Verify(target, version.Refs, version.Head, version.CommitHash)
+}
+
+func (c Controller) versionLocations(archiveId string, versions []types.VersionMetaData, version types.VersionMetaData) ([]types.StorageLocation, error) {
+	if len(version.Locations) > 0 {
+		return version.Locations, nil
+	}
+	if version.Version == versions[len(versions)-1].Version {
+		return types.Locations(c.Storage, archiveId), nil
+	}
+	return nil, fmt.Errorf("version %d: no storage locations recorded", version.Version)
+}
+
+func (c Controller) archiveLocations(archiveId string, versions []types.VersionMetaData) ([]types.StorageLocation, error) {
+	if len(versions) == 0 {
+		return types.Locations(c.Storage, archiveId), nil
+	}
+	return c.versionLocations(archiveId, versions, versions[len(versions)-1])
+}
+
+func (c Controller) restoreChain(archiveId string, versions []types.VersionMetaData, version types.VersionMetaData, target string) error {
+	ancestry, err := chain(versions, version)
+	if err != nil {
+		return err
+	}
+
+	directory, err := os.MkdirTemp("", "ccg-chain-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(directory)
+	}()
+
+	bundles := make([]string, 0, len(ancestry))
+	for _, ancestor := range ancestry {
+		locations, err := c.versionLocations(archiveId, versions, ancestor)
+		if err != nil {
+			return err
+		}
+
+		bundle := filepath.Join(directory, fmt.Sprintf("v%d.bundle", ancestor.Version))
+		err = c.downloadVersion(ancestor, locations, bundle)
+		if err != nil {
+			return fmt.Errorf("version %d: %v", ancestor.Version, err)
+		}
+		bundles = append(bundles, bundle)
+	}
+
+	return git.RestoreBundles(bundles, git.RefList(version.Refs), version.Head, target)
+}
+
+func (c Controller) reassemble(archiveId string, versions []types.VersionMetaData, version types.VersionMetaData, output string) error {
+	directory, err := os.MkdirTemp("", "ccg-reassemble-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(directory)
+	}()
+
+	repositoryPath := filepath.Join(directory, "repository")
+	err = c.restoreChain(archiveId, versions, version, repositoryPath)
+	if err != nil {
+		return err
+	}
+
+	err = verifyRestore(repositoryPath, &version)
+	if err != nil {
+		return err
+	}
+
+	return git.CreateBundle(repositoryPath, output, nil, false)
+}
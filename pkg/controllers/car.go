@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"ethglobal/pkg/car"
+	"ethglobal/pkg/types"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func packCar(reader io.Reader, file *os.File) (string, int64, error) {
+	carWriter, err := car.NewWriter(file)
+	if err != nil {
+		return "", 0, err
+	}
+
+	_, err = io.Copy(carWriter, reader)
+	if err != nil {
+		return "", 0, err
+	}
+
+	root, err := carWriter.Finish()
+	if err != nil {
+		return "", 0, err
+	}
+
+	return root.String(), carWriter.Size(), nil
+}
+
+func (c Controller) uploadCar(importer types.CarImporter, reader io.Reader, name string) (types.UploadResult, error) {
+	if c.CarDirectory != "" {
+		err := os.MkdirAll(c.CarDirectory, 0755)
+		if err != nil {
+			return types.UploadResult{}, err
+		}
+	}
+
+	file, err := os.CreateTemp(c.CarDirectory, ".car-*")
+	if err != nil {
+		return types.UploadResult{}, err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	root, size, err := packCar(reader, file)
+	if err != nil {
+		return types.UploadResult{}, err
+	}
+
+	if c.CarDirectory != "" {
+		kept := filepath.Join(c.CarDirectory, root+".car")
+		err = os.Link(file.Name(), kept)
+		if err != nil && !os.IsExist(err) {
+			return types.UploadResult{}, err
+		}
+		log.Printf("wrote %s", kept)
+	}
+
+	if importer.Has(root) {
+		log.Printf("%s already stored, skipping upload", root)
+		return types.UploadResult{Name: name, Cid: root, Size: size}, nil
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return types.UploadResult{}, err
+	}
+
+	result, err := importer.PutCar(name, root, file)
+	if err != nil {
+		return types.UploadResult{}, err
+	}
+
+	result.Size = size
+	return result, nil
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"ethglobal/pkg/types"
+	"ethglobal/pkg/utils"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+func (c Controller) journalPath(repository string, digest string) string {
+	hash := utils.SHA256(repository)
+	return filepath.Join(c.JournalDirectory, fmt.Sprintf("%s-%s.json", hex.EncodeToString(hash[:]), digest))
+}
+
+func (c Controller) uploadChunk(archive *os.File, manifest *types.ChunkManifest, index int) (types.ChunkEntry, error) {
+	offset := int64(index) * manifest.ChunkSize
+	size := min(manifest.ChunkSize, manifest.Size-offset)
+
+	var err error
+	for attempt := 0; attempt <= c.ChunkRetries; attempt++ {
+		if attempt > 0 {
+			log.Printf("retrying chunk %d (attempt %d): %v", index, attempt+1, err)
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+
+		var digest []byte
+		if c.Convergent {
+			hash := sha256.New()
+			_, err = io.Copy(hash, io.NewSectionReader(archive, offset, size))
+			if err != nil {
+				continue
+			}
+			digest = hash.Sum(nil)
+		}
+
+		hash := sha256.New()
+		section := io.TeeReader(io.NewSectionReader(archive, offset, size), hash)
+
+		var result types.UploadResult
+		result, err = c.uploadStream(section, digest, fmt.Sprintf("%s.part%d", manifest.Name, index))
+		if err == nil {
+			return types.ChunkEntry{
+				Index:  index,
+				Size:   size,
+				Sha256: hex.EncodeToString(hash.Sum(nil)),
+				Id:     result.Cid,
+
+				Locations: result.Locations,
+			}, nil
+		}
+	}
+
+	return types.ChunkEntry{}, fmt.Errorf("failed to upload chunk %d: %v", index, err)
+}
+
+func (c Controller) pushChunked(repository string, dotGitFile string, name string) (types.UploadResult, *types.UploadJournal, error) {
+	digest, size, err := utils.FileSHA256(dotGitFile)
+	if err != nil {
+		return types.UploadResult{}, nil, err
+	}
+
+	journal, err := types.LoadUploadJournal(c.journalPath(repository, digest))
+	if err != nil {
+		return types.UploadResult{}, nil, err
+	}
+
+	if journal.Manifest.ChunkSize != c.ChunkSize || journal.Manifest.Sha256 != digest {
+		journal.Manifest = types.ChunkManifest{
+			Name:      name,
+			ChunkSize: c.ChunkSize,
+			Size:      size,
+			Sha256:    digest,
+		}
+	} else if len(journal.Manifest.Chunks) > 0 {
+		log.Printf("resuming upload, %d of %d chunks already stored", len(journal.Manifest.Chunks), journal.Manifest.ChunkCount())
+	}
+
+	archive, err := os.Open(dotGitFile)
+	if err != nil {
+		return types.UploadResult{}, nil, err
+	}
+	defer func(archive *os.File) {
+		_ = archive.Close()
+	}(archive)
+
+	for index := 0; index < journal.Manifest.ChunkCount(); index++ {
+		if _, ok := journal.Manifest.Chunk(index); ok {
+			continue
+		}
+
+		chunk, err := c.uploadChunk(archive, &journal.Manifest, index)
+		if err != nil {
+			return types.UploadResult{}, nil, err
+		}
+
+		err = journal.Record(chunk)
+		if err != nil {
+			return types.UploadResult{}, nil, err
+		}
+	}
+
+	sort.Slice(journal.Manifest.Chunks, func(i, j int) bool {
+		return journal.Manifest.Chunks[i].Index < journal.Manifest.Chunks[j].Index
+	})
+
+	marshalledManifest, err := json.Marshal(journal.Manifest)
+	if err != nil {
+		return types.UploadResult{}, nil, err
+	}
+
+	manifest, err := c.upload(marshalledManifest, name+".manifest")
+	if err != nil {
+		return types.UploadResult{}, nil, err
+	}
+
+	return manifest, journal, nil
+}
+
+func (c Controller) chunkLocations(chunk types.ChunkEntry) []types.StorageLocation {
+	if len(chunk.Locations) > 0 {
+		return chunk.Locations
+	}
+	return types.Locations(c.Storage, chunk.Id)
+}
+
+func (c Controller) manifest(locations []types.StorageLocation) (*types.ChunkManifest, error) {
+	marshalledManifest, err := types.GetLocations(c.Storage, locations, c.decrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest types.ChunkManifest
+	err = json.Unmarshal(marshalledManifest, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chunk manifest: %v", err)
+	}
+
+	if len(manifest.Chunks) != manifest.ChunkCount() {
+		return nil, fmt.Errorf("chunk manifest lists %d of %d chunks", len(manifest.Chunks), manifest.ChunkCount())
+	}
+	return &manifest, nil
+}
+
+func (c Controller) downloadChunked(locations []types.StorageLocation, output string) error {
+	manifest, err := c.manifest(locations)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(output), ".pull-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = temp.Close()
+		_ = os.Remove(temp.Name())
+	}()
+
+	for index := 0; index < manifest.ChunkCount(); index++ {
+		chunk, ok := manifest.Chunk(index)
+		if !ok {
+			return fmt.Errorf("chunk manifest is missing chunk %d", index)
+		}
+
+		offset := int64(index) * manifest.ChunkSize
+		err = types.OpenLocations(c.Storage, c.chunkLocations(chunk), func(reader io.Reader) error {
+			err := temp.Truncate(offset)
+			if err != nil {
+				return err
+			}
+			_, err = temp.Seek(offset, io.SeekStart)
+			if err != nil {
+				return err
+			}
+
+			hash := sha256.New()
+			written, err := c.decryptTo(io.MultiWriter(temp, hash), reader)
+			if err != nil {
+				return fmt.Errorf("chunk %d: %w", index, err)
+			}
+
+			if written != chunk.Size || hex.EncodeToString(hash.Sum(nil)) != chunk.Sha256 {
+				return fmt.Errorf("chunk %d does not match manifest", index)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = temp.Close()
+	if err != nil {
+		return err
+	}
+
+	digest, _, err := utils.FileSHA256(temp.Name())
+	if err != nil {
+		return err
+	}
+	if digest != manifest.Sha256 {
+		return fmt.Errorf("reassembled archive digest %s does not match manifest %s", digest, manifest.Sha256)
+	}
+
+	err = os.Chmod(temp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), output)
+}
@@ -1,44 +1,230 @@
 package controllers
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
+	"ethglobal/pkg/git"
 	"ethglobal/pkg/types"
+	"ethglobal/pkg/unixfs"
 	"ethglobal/pkg/utils"
+	"fmt"
+	"io"
+	"log"
 	"os"
+	"path/filepath"
+	"time"
 )
 
 type Controller struct {
 	EncryptionKeyBytes []byte
 	ActionContracts    *types.ContractActions
+	Storage            types.StorageBackend
 	Lighthouse         *types.LighthouseClient
+	ChunkSize          int64
+	ChunkRetries       int
+	JournalDirectory   string
+	LedgerDirectory    string
+	CarUploads         bool
+	CarDirectory       string
+	ReleaseTags        []string
+	Reproducible       bool
+	Convergent         bool
 }
 
-func (c Controller) calculateMetaData(hash [32]byte, commitHash string) ([]byte, error) {
-	metaData, exists, err := c.ActionContracts.GetProjectMetadata(hash)
+func (c Controller) upload(plainBuf []byte, name string) (types.UploadResult, error) {
+	encrypt := utils.Encrypt
+	if c.Convergent {
+		encrypt = utils.EncryptConvergent
+	}
+
+	cipherText, err := encrypt(c.EncryptionKeyBytes, plainBuf)
 	if err != nil {
-		return nil, err
+		return types.UploadResult{}, err
 	}
 
-	var versions []types.VersionMetaData
-	if exists {
-		err = json.Unmarshal(metaData, &versions)
+	return c.Storage.Put(name, cipherText)
+}
+
+func (c Controller) encrypted(reader io.Reader, digest []byte) io.ReadCloser {
+	pipeReader, pipeWriter := io.Pipe()
+
+	go func() {
+		var encrypter io.WriteCloser
+		var err error
+		if digest != nil {
+			encrypter, err = utils.NewConvergentEncryptWriter(c.EncryptionKeyBytes, digest, pipeWriter)
+		} else {
+			encrypter, err = utils.NewEncryptWriter(c.EncryptionKeyBytes, pipeWriter)
+		}
 		if err != nil {
-			return nil, err
+			_ = pipeWriter.CloseWithError(err)
+			return
 		}
 
-		next := types.VersionMetaData{
-			Version:    uint32(len(versions) + 1),
-			CommitHash: commitHash,
+		_, err = io.Copy(encrypter, reader)
+		if err != nil {
+			_ = pipeWriter.CloseWithError(err)
+			return
 		}
-		versions = append(versions, next)
-	} else {
-		next := types.VersionMetaData{
-			Version:    1,
-			CommitHash: commitHash,
+
+		_ = pipeWriter.CloseWithError(encrypter.Close())
+	}()
+
+	return pipeReader
+}
+
+func (c Controller) uploadStream(reader io.Reader, digest []byte, name string) (types.UploadResult, error) {
+	cipherReader := c.encrypted(reader, digest)
+	defer func(cipherReader io.ReadCloser) {
+		_ = cipherReader.Close()
+	}(cipherReader)
+
+	if importer, ok := c.Storage.(types.CarImporter); ok && c.CarUploads {
+		return c.uploadCar(importer, cipherReader, name)
+	}
+	return c.Storage.PutStream(name, cipherReader)
+}
+
+func (c Controller) uploadFile(path string, name string) (types.UploadResult, error) {
+	archive, err := os.Open(path)
+	if err != nil {
+		return types.UploadResult{}, err
+	}
+	defer func(archive *os.File) {
+		_ = archive.Close()
+	}(archive)
+
+	var digest []byte
+	if c.Convergent {
+		hash := sha256.New()
+		_, err = io.Copy(hash, archive)
+		if err == nil {
+			_, err = archive.Seek(0, io.SeekStart)
 		}
-		versions = append(versions, next)
+		if err != nil {
+			return types.UploadResult{}, err
+		}
+		digest = hash.Sum(nil)
+	}
+
+	return c.uploadStream(archive, digest, name)
+}
+
+func (c Controller) downloadStream(locations []types.StorageLocation, output string) error {
+	temp, err := os.CreateTemp(filepath.Dir(output), ".pull-*")
+	if err != nil {
+		return err
 	}
+	defer func() {
+		_ = temp.Close()
+		_ = os.Remove(temp.Name())
+	}()
+
+	err = types.OpenLocations(c.Storage, locations, func(reader io.Reader) error {
+		_, err := temp.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		err = temp.Truncate(0)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.decryptTo(temp, reader)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = temp.Chmod(0644)
+	if err != nil {
+		return err
+	}
+
+	err = temp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), output)
+}
+
+func (c Controller) decryptTo(writer io.Writer, reader io.Reader) (int64, error) {
+	decrypter, err := utils.NewDecryptReader(c.EncryptionKeyBytes, reader)
+	if err == nil {
+		var written int64
+		written, err = io.Copy(writer, decrypter)
+		if err == nil {
+			return written, nil
+		}
+	}
+
+	if errors.Is(err, unixfs.ErrWrongContent) {
+		return 0, err
+	}
+
+	_, drainErr := io.Copy(io.Discard, reader)
+	if errors.Is(drainErr, unixfs.ErrWrongContent) {
+		return 0, drainErr
+	}
+
+	return 0, fmt.Errorf("failed to decrypt file: %v", err)
+}
+
+func (c Controller) decrypt(cipherBuf []byte) ([]byte, error) {
+	plainBuf, err := utils.Decrypt(c.EncryptionKeyBytes, cipherBuf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt file: %v", err)
+	}
+
+	return plainBuf, nil
+}
+
+func (c Controller) download(id string) ([]byte, error) {
+	return types.GetVerified(c.Storage, id, c.decrypt)
+}
+
+func (c Controller) metaData(hash [32]byte) (string, []types.VersionMetaData, error) {
+	metaDataCid, exists, err := c.ActionContracts.GetProjectMetadata(hash)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var versions []types.VersionMetaData
+	if !exists {
+		return "", versions, nil
+	}
+
+	metaDataId, err := types.DecodeReference(metaDataCid)
+	if err != nil {
+		return "", nil, err
+	}
+
+	metaData, err := c.download(metaDataId)
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = json.Unmarshal(metaData, &versions)
+	if err != nil {
+		return "", nil, err
+	}
+	return metaDataId, versions, nil
+}
+
+func (c Controller) versions(hash [32]byte) ([]types.VersionMetaData, error) {
+	_, versions, err := c.metaData(hash)
+	return versions, err
+}
+
+func (c Controller) calculateMetaData(metaDataId string, versions []types.VersionMetaData, next types.VersionMetaData) ([]byte, error) {
+	if metaDataId != "" {
+		next.PreviousMetaData = types.Locations(c.Storage, metaDataId)
+	}
+	next.Version = uint32(len(versions) + 1)
+	versions = append(versions, next)
 
 	marshalledMetaData, err := json.Marshal(versions)
 	if err != nil {
@@ -47,37 +233,168 @@ func (c Controller) calculateMetaData(hash [32]byte, commitHash string) ([]byte,
 	return marshalledMetaData, nil
 }
 
-func (c Controller) PushColdStorage(repository string, dotGitFile string, commitHash string) (string, error) {
+func unchanged(versions []types.VersionMetaData, next types.VersionMetaData) bool {
+	if len(versions) == 0 {
+		return false
+	}
+
+	latest := versions[len(versions)-1]
+	if latest.CommitHash != next.CommitHash || (next.Release && !latest.Release) {
+		return false
+	}
+
+	return (next.ArchiveSha256 != "" && latest.ArchiveSha256 == next.ArchiveSha256) || (next.Fingerprint != "" && latest.Fingerprint == next.Fingerprint)
+}
+
+func unchangedRefs(versions []types.VersionMetaData, next types.VersionMetaData, format string) bool {
+	if len(versions) == 0 || len(next.Refs) == 0 {
+		return false
+	}
+
+	latest := versions[len(versions)-1]
+	if latest.Format != format || latest.Head != next.Head || len(latest.Refs) == 0 {
+		return false
+	}
+
+	latest.ArchiveSha256, next.ArchiveSha256 = "", ""
+	latest.Fingerprint = git.RefsFingerprint(git.RefList(latest.Refs))
+	next.Fingerprint = git.RefsFingerprint(git.RefList(next.Refs))
+	return unchanged([]types.VersionMetaData{latest}, next)
+}
 
+func (c Controller) PushColdStorage(repository string, dotGitFile string, commitHash string, options types.PushOptions) (string, error) {
 	hash := utils.SHA256(repository)
-	marshalledMetaData, err := c.calculateMetaData(hash, commitHash)
+
+	metaDataId, versions, err := c.metaData(hash)
 	if err != nil {
 		return "", err
 	}
 
-	bytes, err := os.ReadFile(dotGitFile)
+	next := types.VersionMetaData{
+		CommitHash: commitHash,
+		Release:    options.Release,
+	}
+
+	if info, err := os.Stat(dotGitFile); err == nil && info.IsDir() {
+		err = describeRepository(dotGitFile, &next)
+		if err != nil {
+			return "", err
+		}
+
+		if !c.releasePolicy(dotGitFile, versions, &next, options) {
+			return "", nil
+		}
+
+		format := git.FormatBundle
+		if options.Format == git.FormatTarball {
+			format = git.FormatTarball
+		}
+
+		if !options.Force && unchangedRefs(versions, next, format) {
+			log.Printf("repository unchanged since version %d, skipping push", versions[len(versions)-1].Version)
+			return "", nil
+		}
+
+		directory, err := os.MkdirTemp("", "ccg-bundle-*")
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			_ = os.RemoveAll(directory)
+		}()
+
+		archivePath := filepath.Join(directory, commitHash+".bundle")
+		if format == git.FormatTarball {
+			archivePath = filepath.Join(directory, commitHash+".tar.gz")
+			err = git.CreateTarball(dotGitFile, archivePath)
+		} else {
+			err = git.CreateBundle(dotGitFile, archivePath, exclusions(versions, options), c.Reproducible || c.ChunkSize > 0)
+		}
+		if err != nil {
+			return "", err
+		}
+		dotGitFile = archivePath
+	}
+
+	next.ArchiveSha256, next.Size, err = utils.FileSHA256(dotGitFile)
 	if err != nil {
 		return "", err
 	}
 
-	cid, err := c.Lighthouse.UploadFile(bytes, c.EncryptionKeyBytes, commitHash)
+	next.Format, err = git.Format(dotGitFile)
 	if err != nil {
 		return "", err
 	}
 
-	metaDataCid, err := c.Lighthouse.UploadFile(marshalledMetaData, c.EncryptionKeyBytes, commitHash+"_meta")
+	if next.Format == git.FormatBundle {
+		err = describeBundle(dotGitFile, versions, &next)
+	} else {
+		next.Fingerprint, err = git.Fingerprint(dotGitFile)
+	}
 	if err != nil {
 		return "", err
 	}
 
-	transactionId, err := c.ActionContracts.SetProject(hash, []byte(cid), []byte(metaDataCid))
+	if !options.Force && unchanged(versions, next) {
+		log.Printf("repository unchanged since version %d, skipping push", versions[len(versions)-1].Version)
+		return "", nil
+	}
+
+	var archive types.UploadResult
+	var journal *types.UploadJournal
+	if c.ChunkSize > 0 {
+		archive, journal, err = c.pushChunked(repository, dotGitFile, commitHash+".git")
+	} else {
+		archive, err = c.uploadFile(dotGitFile, commitHash+".git")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	next.Locations = types.ResultLocations(c.Storage, archive)
+	next.Chunked = journal != nil
+	next.Timestamp = time.Now().Unix()
+	c.backfillTransactions(hash, versions)
+	marshalledMetaData, err := c.calculateMetaData(metaDataId, versions, next)
+	if err != nil {
+		return "", err
+	}
+
+	metaData, err := c.upload(marshalledMetaData, commitHash+"_meta.git")
 	if err != nil {
 		return "", err
 	}
 
+	transactionId, err := c.ActionContracts.SetProject(hash, types.EncodeReference(archive.Cid), types.EncodeReference(metaData.Cid))
+	if err != nil {
+		return "", err
+	}
+
+	next.Version = uint32(len(versions) + 1)
+	c.recordTransaction(hash, next, transactionId)
+
+	if journal != nil {
+		err = journal.Remove()
+		if err != nil {
+			log.Printf("failed to remove upload journal: %v", err)
+		}
+	}
+
 	return transactionId, nil
 }
 
+func (c Controller) PushRepository(repository string, repositoryPath string, options types.PushOptions) (string, error) {
+	if !git.IsRepository(repositoryPath) {
+		return "", fmt.Errorf("%s is not a git repository", repositoryPath)
+	}
+
+	commitHash, err := git.HeadCommit(repositoryPath)
+	if err != nil {
+		return "", err
+	}
+	return c.PushColdStorage(repository, repositoryPath, commitHash, options)
+}
+
 func (c Controller) RetrieveLatestMetaData(repository string) ([]byte, error) {
 	hash := utils.SHA256(repository)
 	metaDataCid, exists, err := c.ActionContracts.GetProjectMetadata(hash)
@@ -86,7 +403,12 @@ func (c Controller) RetrieveLatestMetaData(repository string) ([]byte, error) {
 	}
 
 	if exists {
-		metaData, err := c.Lighthouse.DownloadFile(string(metaDataCid), c.EncryptionKeyBytes)
+		metaDataId, err := types.DecodeReference(metaDataCid)
+		if err != nil {
+			return nil, err
+		}
+
+		metaData, err := c.download(metaDataId)
 		if err != nil {
 			return nil, err
 		}
@@ -97,28 +419,97 @@ func (c Controller) RetrieveLatestMetaData(repository string) ([]byte, error) {
 	}
 }
 
-func (c Controller) RetrieveColdStorage(repository string, output string) ([]byte, error) {
+func (c Controller) retrieve(repository string, output string, selector types.VersionSelector) ([]byte, *types.VersionMetaData, error) {
 	hash := utils.SHA256(repository)
 	cid, metaDataCid, exists, err := c.ActionContracts.GetProject(hash)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if !exists {
-		return nil, errors.New("failed to retrieve project code")
+		return nil, nil, errors.New("failed to retrieve project code")
+	}
+
+	archiveId, err := types.DecodeReference(cid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metaDataId, err := types.DecodeReference(metaDataCid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metaData, err := c.download(metaDataId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var versions []types.VersionMetaData
+	err = json.Unmarshal(metaData, &versions)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	data, err := c.Lighthouse.DownloadFile(string(cid), c.EncryptionKeyBytes)
+	if len(versions) == 0 && selector == (types.VersionSelector{}) {
+		err = c.downloadStream(types.Locations(c.Storage, archiveId), output)
+		if err != nil {
+			return nil, nil, err
+		}
+		return metaData, nil, nil
+	}
+
+	version, err := selector.Select(versions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if version.Parent != 0 {
+		err = c.reassemble(archiveId, versions, version, output)
+	} else {
+		var locations []types.StorageLocation
+		locations, err = c.versionLocations(archiveId, versions, version)
+		if err == nil {
+			err = c.downloadVersion(version, locations, output)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return metaData, &version, nil
+}
+
+func (c Controller) RetrieveColdStorage(repository string, output string, selector types.VersionSelector) ([]byte, error) {
+	metaData, _, err := c.retrieve(repository, output, selector)
+	return metaData, err
+}
+
+func (c Controller) RestoreRepository(repository string, target string, selector types.VersionSelector) ([]byte, error) {
+	directory, err := os.MkdirTemp("", "ccg-restore-*")
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		_ = os.RemoveAll(directory)
+	}()
 
-	metaData, err := c.Lighthouse.DownloadFile(string(metaDataCid), c.EncryptionKeyBytes)
+	archive := filepath.Join(directory, "archive")
+	metaData, version, err := c.retrieve(repository, archive, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != nil && git.IsBundle(archive) {
+		err = git.RestoreBundles([]string{archive}, git.RefList(version.Refs), version.Head, target)
+	} else {
+		err = git.Restore(archive, target)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	err = os.WriteFile(output, data, 0644)
+	err = verifyRestore(target, version)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"errors"
+	"ethglobal/pkg/types"
+	"ethglobal/pkg/utils"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+)
+
+type dealTarget struct {
+	label     string
+	locations []types.StorageLocation
+}
+
+func (t dealTarget) id() string {
+	if len(t.locations) == 0 {
+		return ""
+	}
+	return t.locations[0].Id
+}
+
+func lighthouseCid(locations []types.StorageLocation) (string, bool) {
+	for _, location := range locations {
+		if location.Backend == "lighthouse" {
+			return location.Id, true
+		}
+	}
+
+	for _, location := range locations {
+		if _, err := cid.Decode(location.Id); err == nil {
+			return location.Id, true
+		}
+	}
+	return "", false
+}
+
+func (c Controller) chunkTargets(archive []types.StorageLocation, versions []types.VersionMetaData) ([]dealTarget, error) {
+	if len(versions) == 0 || !versions[len(versions)-1].Chunked {
+		return nil, nil
+	}
+
+	manifest, err := c.manifest(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]dealTarget, 0, len(manifest.Chunks))
+	for _, chunk := range manifest.Chunks {
+		targets = append(targets, dealTarget{label: fmt.Sprintf("chunk %d", chunk.Index), locations: c.chunkLocations(chunk)})
+	}
+	return targets, nil
+}
+
+func (c Controller) RetrieveDeals(repository string) ([]types.CidDeals, error) {
+	if c.Lighthouse == nil {
+		return nil, errors.New("lighthouse client not configured")
+	}
+
+	hash := utils.SHA256(repository)
+	archiveCid, metaDataCid, exists, err := c.ActionContracts.GetProject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, errors.New("failed to retrieve project code")
+	}
+
+	archiveId, err := types.DecodeReference(archiveCid)
+	if err != nil {
+		return nil, err
+	}
+
+	metaDataId, err := types.DecodeReference(metaDataCid)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := c.versions(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := c.archiveLocations(archiveId, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []dealTarget{
+		{label: "archive", locations: archive},
+		{label: "metadata", locations: types.Locations(c.Storage, metaDataId)},
+	}
+
+	chunks, err := c.chunkTargets(archive, versions)
+	if err != nil {
+		return nil, err
+	}
+	targets = append(targets, chunks...)
+
+	results := make([]types.CidDeals, 0, len(targets))
+	for _, target := range targets {
+		result := types.CidDeals{Label: target.label, Cid: target.id()}
+
+		hash, ok := lighthouseCid(target.locations)
+		if !ok {
+			result.Error = "not stored on lighthouse"
+			results = append(results, result)
+			continue
+		}
+
+		result.Cid = hash
+		result.Deals, err = c.Lighthouse.DealStatus(hash)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"errors"
+	"ethglobal/pkg/types"
+	"ethglobal/pkg/utils"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+func (c Controller) ledger(hash [32]byte) (*types.Ledger, error) {
+	return types.LoadLedger(filepath.Join(c.LedgerDirectory, hex.EncodeToString(hash[:])+".json"))
+}
+
+const (
+	transactionSlack  = 20
+	transactionWindow = 2880
+)
+
+func (c Controller) chainTransaction(hash [32]byte, version types.VersionMetaData, archiveId string) (string, error) {
+	if !c.ActionContracts.Updates || version.Timestamp == 0 || archiveId == "" {
+		return "", nil
+	}
+
+	start := max(types.TimeEpoch(c.ActionContracts.Chain, version.Timestamp)-transactionSlack, 0)
+	updates, err := c.ActionContracts.ProjectUpdates(hash, uint64(start), uint64(start+transactionWindow))
+	if err != nil {
+		return "", err
+	}
+
+	for _, update := range updates {
+		id, err := types.DecodeReference(update.Cid)
+		if err == nil && id == archiveId {
+			return update.Transaction, nil
+		}
+	}
+	return "", nil
+}
+
+func versionArchive(versions []types.VersionMetaData, index int, latest string) string {
+	if len(versions[index].Locations) > 0 {
+		return versions[index].Locations[0].Id
+	}
+	if index == len(versions)-1 {
+		return latest
+	}
+	return ""
+}
+
+func (c Controller) backfillTransactions(hash [32]byte, versions []types.VersionMetaData) {
+	ledger, err := c.ledger(hash)
+	if err != nil {
+		log.Printf("failed to read transaction ledger: %v", err)
+		ledger = &types.Ledger{}
+	}
+
+	for index := range versions {
+		if versions[index].Transaction == "" {
+			versions[index].Transaction = ledger.Transaction(versions[index])
+		}
+	}
+
+	if len(versions) == 0 || versions[len(versions)-1].Transaction != "" {
+		return
+	}
+
+	latest := &versions[len(versions)-1]
+	latest.Transaction, err = c.chainTransaction(hash, *latest, versionArchive(versions, len(versions)-1, ""))
+	if err != nil {
+		log.Printf("failed to look up the transaction of version %d: %v", latest.Version, err)
+	}
+}
+
+func (c Controller) recordTransaction(hash [32]byte, version types.VersionMetaData, transaction string) {
+	ledger, err := c.ledger(hash)
+	if err == nil {
+		err = ledger.Record(types.LedgerEntry{
+			Version:     version.Version,
+			CommitHash:  version.CommitHash,
+			Transaction: transaction,
+			RecordedAt:  time.Now().Unix(),
+		})
+	}
+	if err != nil {
+		log.Printf("failed to record transaction %s in the ledger: %v", transaction, err)
+	}
+}
+
+func (c Controller) History(repository string) ([]types.LogEntry, error) {
+	hash := utils.SHA256(repository)
+	archiveCid, _, exists, err := c.ActionContracts.GetProject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, errors.New("failed to retrieve project code")
+	}
+
+	archiveId, err := types.DecodeReference(archiveCid)
+	if err != nil {
+		return nil, err
+	}
+
+	_, versions, err := c.metaData(hash)
+	if err != nil {
+		return nil, err
+	}
+	if !c.ActionContracts.Updates {
+		log.Printf("contract emits no ProjectUpdated events, transactions come from the local ledger only")
+	}
+	c.backfillTransactions(hash, versions)
+
+	entries := make([]types.LogEntry, 0, len(versions))
+	for index := len(versions) - 1; index >= 0; index-- {
+		version := versions[index]
+		if version.Transaction == "" {
+			version.Transaction, err = c.chainTransaction(hash, version, versionArchive(versions, index, archiveId))
+			if err != nil {
+				log.Printf("failed to look up the transaction of version %d: %v", version.Version, err)
+			}
+		}
+
+		entry := types.LogEntry{
+			Version:     version.Version,
+			CommitHash:  version.CommitHash,
+			Refs:        version.Refs,
+			Release:     version.Release,
+			Parent:      version.Parent,
+			Size:        version.Size,
+			Transaction: version.Transaction,
+			Timestamp:   version.Timestamp,
+		}
+
+		entry.Cid = versionArchive(versions, index, archiveId)
+
+		if entry.Transaction != "" && c.ActionContracts.Client != nil {
+			block, timestamp, err := c.ActionContracts.Receipt(entry.Transaction)
+			if err != nil {
+				log.Printf("failed to look up transaction %s: %v", entry.Transaction, err)
+			} else {
+				entry.Block = block
+				entry.Timestamp = timestamp
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
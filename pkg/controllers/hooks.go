@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"errors"
+	"ethglobal/pkg/git"
+	"ethglobal/pkg/types"
+	"ethglobal/pkg/utils"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+func (c Controller) ReceiveHook(root string, name string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	repository, directory, err := git.RepositoryPath(root, name)
+	if err != nil {
+		return err
+	}
+
+	if !git.IsRepository(directory) {
+		return fmt.Errorf("repository %s does not exist", repository)
+	}
+	git.Trust(directory)
+
+	err = git.Serve("receive-pack", directory, stdin, stdout, stderr)
+	if err != nil {
+		return fmt.Errorf("receive-pack: %v", err)
+	}
+
+	err = git.SyncWorktree(directory)
+	if err != nil {
+		log.Printf("failed to update the working tree of %s: %v", repository, err)
+	}
+
+	transactionId, err := c.PushRepository(repository, directory, types.PushOptions{})
+	if err != nil {
+		return fmt.Errorf("archiving %s to cold storage failed: %v", repository, err)
+	}
+
+	if transactionId != "" {
+		log.Printf("archived %s in transaction %s", repository, transactionId)
+	}
+	return nil
+}
+
+func (c Controller) UploadHook(root string, name string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	repository, directory, err := git.RepositoryPath(root, name)
+	if err != nil {
+		return err
+	}
+
+	git.Trust(directory)
+	if git.IsRepository(directory) {
+		err = c.checkArchive(repository, directory)
+	} else {
+		err = c.restoreMissing(repository, directory)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = git.Serve("upload-pack", directory, stdin, stdout, stderr)
+	if err != nil {
+		return fmt.Errorf("upload-pack: %v", err)
+	}
+	return nil
+}
+
+func (c Controller) restoreMissing(repository string, directory string) error {
+	_, statErr := os.Stat(directory)
+
+	_, err := c.RestoreRepository(repository, directory, types.VersionSelector{})
+	if err == nil {
+		return nil
+	}
+
+	if os.IsNotExist(statErr) {
+		_ = os.RemoveAll(directory)
+	}
+	if errors.Is(err, git.ErrIntegrity) {
+		return fmt.Errorf("refusing to serve %s: %v", repository, err)
+	}
+	return fmt.Errorf("restoring %s from cold storage failed: %v", repository, err)
+}
+
+func (c Controller) checkArchive(repository string, directory string) error {
+	versions, err := c.versions(utils.SHA256(repository))
+	if err == nil && len(versions) > 0 && git.AheadOf(directory, versions[len(versions)-1].Refs) {
+		return nil
+	}
+
+	scratch, err := os.MkdirTemp("", "ccg-upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(scratch)
+	}()
+
+	_, err = c.RestoreRepository(repository, scratch, types.VersionSelector{})
+	if errors.Is(err, git.ErrIntegrity) {
+		return fmt.Errorf("refusing to serve %s: %v", repository, err)
+	}
+	if err != nil {
+		log.Printf("restoring %s from cold storage failed, serving the local copy: %v", repository, err)
+		return nil
+	}
+
+	diverged, err := git.Reconcile(scratch, directory)
+	if err != nil {
+		log.Printf("failed to bring %s up to date with cold storage: %v", repository, err)
+	}
+	if len(diverged) > 0 {
+		log.Printf("%s has diverged from cold storage on %s, keeping the local refs", repository, strings.Join(diverged, ", "))
+	}
+	return nil
+}
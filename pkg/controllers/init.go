@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"context"
+	"ethglobal/pkg/contract"
+	"ethglobal/pkg/lighthouse"
+	"ethglobal/pkg/storage"
+	"ethglobal/pkg/types"
+	"fmt"
+)
+
+func InitController(configuration types.Configuration) (Controller, *context.Context, error) {
+	actions, rootCtx, err := contract.InitContractActions(&configuration)
+	if err != nil {
+		return Controller{}, nil, err
+	}
+
+	storageBackend, err := storage.InitStorageBackend(configuration)
+	if err != nil {
+		return Controller{}, nil, fmt.Errorf("error initialising storage backend: %v", err)
+	}
+
+	return Controller{
+		ActionContracts:    actions,
+		Storage:            storageBackend,
+		Lighthouse:         lighthouse.InitLightHouseClient(configuration),
+		EncryptionKeyBytes: []byte(configuration.EncryptionKey),
+		ChunkSize:          configuration.ChunkSize,
+		ChunkRetries:       configuration.ChunkRetries,
+		JournalDirectory:   configuration.JournalDirectory,
+		LedgerDirectory:    configuration.LedgerDirectory,
+		CarUploads:         configuration.CarUploads,
+		CarDirectory:       configuration.CarDirectory,
+		ReleaseTags:        configuration.ReleaseTags,
+		Reproducible:       configuration.ReproducibleArchives,
+		Convergent:         configuration.ConvergentEncryption,
+	}, rootCtx, nil
+}
@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"errors"
+	"ethglobal/pkg/types"
+	"ethglobal/pkg/utils"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ipfs/go-cid"
+)
+
+func (c Controller) pinTargets(repository string) ([]types.PinTarget, error) {
+	hash := utils.SHA256(repository)
+	archiveCid, _, exists, err := c.ActionContracts.GetProject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, errors.New("failed to retrieve project code")
+	}
+
+	archiveId, err := types.DecodeReference(archiveCid)
+	if err != nil {
+		return nil, err
+	}
+
+	metaDataId, versions, err := c.metaData(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []types.PinTarget{{
+		Label:     "metadata",
+		Keep:      true,
+		Locations: types.Locations(c.Storage, metaDataId),
+	}}
+
+	kept := map[uint32]bool{}
+	for index, version := range versions {
+		if index != len(versions)-1 && !version.Release && !c.tagsRelease(versions, index) {
+			continue
+		}
+
+		ancestry, err := chain(versions, version)
+		if err != nil {
+			return nil, err
+		}
+		for _, ancestor := range ancestry {
+			kept[ancestor.Version] = true
+		}
+	}
+
+	for index, version := range versions {
+		latest := index == len(versions)-1
+		keep := kept[version.Version]
+
+		locations := version.Locations
+		if len(locations) == 0 && latest {
+			locations = types.Locations(c.Storage, archiveId)
+		}
+
+		if len(locations) > 0 {
+			label := fmt.Sprintf("v%d archive", version.Version)
+			if version.Chunked {
+				label = fmt.Sprintf("v%d manifest", version.Version)
+			}
+			targets = append(targets, types.PinTarget{Label: label, Version: version.Version, Keep: keep, Locations: locations})
+		}
+
+		if version.Chunked && len(locations) > 0 {
+			manifest, err := c.manifest(locations)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read chunk manifest of version %d: %w", version.Version, err)
+			}
+
+			for _, chunk := range manifest.Chunks {
+				targets = append(targets, types.PinTarget{
+					Label:     fmt.Sprintf("v%d chunk %d", version.Version, chunk.Index),
+					Version:   version.Version,
+					Keep:      keep,
+					Locations: c.chunkLocations(chunk),
+				})
+			}
+		}
+
+		if len(version.PreviousMetaData) > 0 {
+			targets = append(targets, types.PinTarget{
+				Label:     fmt.Sprintf("v%d superseded metadata", version.Version),
+				Version:   version.Version,
+				Locations: version.PreviousMetaData,
+			})
+		}
+	}
+
+	return targets, nil
+}
+
+func (c Controller) ListPins(repository string) ([]types.PinStatus, error) {
+	targets, err := c.pinTargets(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []types.PinStatus
+	for _, target := range targets {
+		for _, location := range target.Locations {
+			status := target.Status(location)
+
+			backend := types.Backend(c.Storage, location.Backend)
+			if backend == nil {
+				status.Error = "backend not configured"
+				statuses = append(statuses, status)
+				continue
+			}
+
+			stat, err := backend.Stat(location.Id)
+			if err != nil {
+				status.Error = err.Error()
+			} else {
+				status.Pinned = stat.Pinned
+				status.Size = stat.Size
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
+
+func selectTargets(targets []types.PinTarget, version uint32) ([]types.PinTarget, error) {
+	if version == 0 {
+		return targets, nil
+	}
+
+	var selected []types.PinTarget
+	for _, target := range targets {
+		if target.Version == version {
+			selected = append(selected, target)
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("version %d has no recorded storage locations", version)
+	}
+	return selected, nil
+}
+
+func (c Controller) Unpin(repository string, version uint32) ([]types.PinStatus, error) {
+	all, err := c.pinTargets(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := selectTargets(all, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != 0 {
+		var removable []types.PinTarget
+		for _, target := range targets {
+			if !target.Keep {
+				removable = append(removable, target)
+			}
+		}
+
+		if len(removable) == 0 {
+			return nil, fmt.Errorf("version %d is the latest version, a release or a base they build on and stays pinned", version)
+		}
+		targets = removable
+	}
+
+	kept := map[types.StorageLocation]bool{}
+	for _, target := range all {
+		for _, location := range target.Locations {
+			kept[location] = kept[location] || target.Keep
+		}
+	}
+
+	var statuses []types.PinStatus
+	for _, target := range targets {
+		if target.Keep {
+			continue
+		}
+
+		for _, location := range target.Locations {
+			status := target.Status(location)
+			if kept[location] {
+				status.Pinned = true
+				statuses = append(statuses, status)
+				continue
+			}
+
+			backend := types.Backend(c.Storage, location.Backend)
+			if backend == nil {
+				status.Error = "backend not configured"
+			} else if err := backend.Delete(location.Id); err != nil {
+				status.Error = err.Error()
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
+
+func (c Controller) fetchTarget(target types.PinTarget) (*os.File, error) {
+	content, err := os.CreateTemp("", "ccg-repin-*")
+	if err != nil {
+		return nil, err
+	}
+
+	err = types.OpenLocations(c.Storage, target.Locations, func(reader io.Reader) error {
+		err := content.Truncate(0)
+		if err != nil {
+			return err
+		}
+		_, err = content.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(content, reader)
+		return err
+	})
+	if err != nil {
+		_ = content.Close()
+		_ = os.Remove(content.Name())
+		return nil, err
+	}
+
+	return content, nil
+}
+
+func (c Controller) reupload(backend types.StorageBackend, location types.StorageLocation, name string, content *os.File) error {
+	_, err := content.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	var result types.UploadResult
+	parsed, parseErr := cid.Decode(location.Id)
+	if importer, ok := backend.(types.CarImporter); ok && parseErr == nil && parsed.Version() == 1 {
+		result, err = c.uploadCar(importer, content, name)
+	} else {
+		result, err = backend.PutStream(name, content)
+	}
+	if err != nil {
+		return err
+	}
+
+	if result.Cid != location.Id {
+		return fmt.Errorf("content re-uploaded as %s instead of %s", result.Cid, location.Id)
+	}
+	return nil
+}
+
+func (c Controller) Repin(repository string, version uint32) ([]types.PinStatus, error) {
+	all, err := c.pinTargets(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := selectTargets(all, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []types.PinStatus
+	for _, target := range targets {
+		var content *os.File
+
+		for _, location := range target.Locations {
+			status := target.Status(location)
+
+			backend := types.Backend(c.Storage, location.Backend)
+			if backend == nil {
+				status.Error = "backend not configured"
+				statuses = append(statuses, status)
+				continue
+			}
+
+			if pinner, ok := backend.(types.Pinner); ok {
+				err = pinner.Pin(location.Id)
+			} else if stat, statErr := backend.Stat(location.Id); statErr == nil && stat.Pinned {
+				err = nil
+			} else {
+				if content == nil {
+					content, err = c.fetchTarget(target)
+				}
+				if content != nil {
+					err = c.reupload(backend, location, target.Label, content)
+				}
+			}
+
+			if err != nil {
+				status.Error = err.Error()
+			} else {
+				status.Pinned = true
+			}
+			statuses = append(statuses, status)
+		}
+
+		if content != nil {
+			_ = content.Close()
+			_ = os.Remove(content.Name())
+		}
+	}
+
+	return statuses, nil
+}
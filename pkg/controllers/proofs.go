@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"ethglobal/pkg/podsi"
+	"ethglobal/pkg/types"
+	"ethglobal/pkg/utils"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func (c Controller) network() string {
+	if c.ActionContracts.Chain != nil && c.ActionContracts.Chain.Int64() == 314159 {
+		return "testnet"
+	}
+	return "mainnet"
+}
+
+func (c Controller) prove(target dealTarget) types.CidProof {
+	result := types.CidProof{Label: target.label, Cid: target.id()}
+
+	hash, ok := lighthouseCid(target.locations)
+	if !ok {
+		result.Error = "not stored on lighthouse"
+		return result
+	}
+	result.Cid = hash
+
+	proof, err := c.Lighthouse.Proof(hash, c.network())
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	pieces, err := c.pieces(target.locations, hash)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	record, err := proof.Verify(hash, pieces)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	record.Deals, err = c.confirmDeals(record)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	record.VerifiedAt = time.Now().Unix()
+	result.Record = &record
+	return result
+}
+
+func (c Controller) pieces(locations []types.StorageLocation, hash string) ([]podsi.Piece, error) {
+	file, err := os.CreateTemp(c.CarDirectory, ".car-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	var raw *podsi.Calculator
+	var root string
+	err = types.OpenLocations(c.Storage, locations, func(reader io.Reader) error {
+		err := file.Truncate(0)
+		if err != nil {
+			return err
+		}
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+
+		raw = podsi.NewCalculator()
+		root, _, err = packCar(io.TeeReader(reader, raw), file)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	piece, err := raw.Sum()
+	if err != nil {
+		return nil, err
+	}
+	pieces := []podsi.Piece{piece}
+
+	if root != hash {
+		return pieces, nil
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	packed := podsi.NewCalculator()
+	_, err = io.Copy(packed, file)
+	if err != nil {
+		return nil, err
+	}
+
+	piece, err = packed.Sum()
+	if err != nil {
+		return nil, err
+	}
+	return append(pieces, piece), nil
+}
+
+func (c Controller) confirmDeals(record types.InclusionRecord) ([]types.InclusionDeal, error) {
+	var confirmed []types.InclusionDeal
+	var failures []string
+	for _, deal := range record.Deals {
+		onChain, err := c.ActionContracts.MarketDeal(deal.DealId)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+
+		if onChain.Proposal.PieceCid.Root != record.AggregateCid || onChain.Proposal.PieceSize != record.AggregateSize {
+			failures = append(failures, fmt.Sprintf("deal %d is for piece %s (%d bytes)", deal.DealId, onChain.Proposal.PieceCid.Root, onChain.Proposal.PieceSize))
+			continue
+		}
+
+		deal.Provider = onChain.Proposal.Provider
+		confirmed = append(confirmed, deal)
+	}
+
+	if len(confirmed) == 0 {
+		if len(failures) == 0 {
+			return nil, fmt.Errorf("aggregate %s has no deals to confirm it on-chain yet", record.AggregateCid)
+		}
+		return nil, fmt.Errorf("aggregate %s isn't confirmed by any on-chain deal: %s", record.AggregateCid, strings.Join(failures, "; "))
+	}
+	return confirmed, nil
+}
+
+func recordProof(proofs []types.InclusionRecord, record types.InclusionRecord) []types.InclusionRecord {
+	for index, proof := range proofs {
+		if proof.Cid == record.Cid {
+			proofs[index] = record
+			return proofs
+		}
+	}
+	return append(proofs, record)
+}
+
+func (c Controller) ProveInclusion(repository string) ([]types.CidProof, string, error) {
+	if c.Lighthouse == nil {
+		return nil, "", errors.New("lighthouse client not configured")
+	}
+
+	hash := utils.SHA256(repository)
+	archiveCid, _, exists, err := c.ActionContracts.GetProject(hash)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !exists {
+		return nil, "", errors.New("failed to retrieve project code")
+	}
+
+	archiveId, err := types.DecodeReference(archiveCid)
+	if err != nil {
+		return nil, "", err
+	}
+
+	metaDataId, versions, err := c.metaData(hash)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(versions) == 0 {
+		return nil, "", errors.New("repository has no archived versions")
+	}
+
+	archive, err := c.archiveLocations(archiveId, versions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	chunks, err := c.chunkTargets(archive, versions)
+	if err != nil {
+		return nil, "", err
+	}
+	targets := append([]dealTarget{{label: "archive", locations: archive}}, chunks...)
+
+	latest := &versions[len(versions)-1]
+	results := make([]types.CidProof, 0, len(targets))
+	verified := 0
+	for _, target := range targets {
+		result := c.prove(target)
+		if result.Record != nil {
+			latest.Proofs = recordProof(latest.Proofs, *result.Record)
+			verified++
+		}
+		results = append(results, result)
+	}
+
+	if verified == 0 {
+		return results, "", nil
+	}
+
+	latest.PreviousMetaData = append(latest.PreviousMetaData, types.Locations(c.Storage, metaDataId)...)
+	c.backfillTransactions(hash, versions)
+
+	marshalledMetaData, err := json.Marshal(versions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	metaData, err := c.upload(marshalledMetaData, latest.CommitHash+"_meta.git")
+	if err != nil {
+		return nil, "", err
+	}
+
+	transactionId, err := c.ActionContracts.SetProject(hash, archiveCid, types.EncodeReference(metaData.Cid))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return results, transactionId, nil
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"ethglobal/pkg/types"
+	"ethglobal/pkg/utils"
+	"fmt"
+)
+
+func matchRef(refs map[string]string, name string) (string, string, bool) {
+	for _, candidate := range []string{name, "refs/tags/" + name, "refs/heads/" + name} {
+		if oid, ok := refs[candidate]; ok {
+			return candidate, oid, true
+		}
+	}
+	return "", "", false
+}
+
+func (c Controller) FindRef(repository string, name string) ([]types.RefVersion, error) {
+	versions, err := c.versions(utils.SHA256(repository))
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []types.RefVersion
+	for _, version := range versions {
+		ref, oid, found := matchRef(version.Refs, name)
+		if !found {
+			continue
+		}
+
+		commit := oid
+		if peeled, ok := version.Peeled[ref]; ok {
+			commit = peeled
+		}
+		matches = append(matches, types.RefVersion{Version: version.Version, Ref: ref, Oid: oid, Commit: commit})
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no archived version contains %s", name)
+	}
+	return matches, nil
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"ethglobal/pkg/git"
+	"ethglobal/pkg/types"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var semver = regexp.MustCompile(`^v?(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+func matchTag(pattern string, ref string) bool {
+	name := strings.TrimPrefix(ref, "refs/tags/")
+	if pattern == "semver" {
+		return semver.MatchString(name)
+	}
+	if strings.HasPrefix(pattern, "refs/") {
+		name = ref
+	}
+
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+func newTags(versions []types.VersionMetaData, refs map[string]string) []string {
+	var previous map[string]string
+	if len(versions) > 0 {
+		previous = versions[len(versions)-1].Refs
+	}
+
+	var tags []string
+	for ref := range refs {
+		if _, exists := previous[ref]; strings.HasPrefix(ref, "refs/tags/") && !exists {
+			tags = append(tags, ref)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (c Controller) tagsRelease(versions []types.VersionMetaData, index int) bool {
+	for _, tag := range newTags(versions[:index], versions[index].Refs) {
+		if len(c.ReleaseTags) == 0 {
+			return true
+		}
+		for _, pattern := range c.ReleaseTags {
+			if matchTag(pattern, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c Controller) releasePolicy(repositoryPath string, versions []types.VersionMetaData, next *types.VersionMetaData, options types.PushOptions) bool {
+	patterns := options.ReleaseTags
+	if patterns == nil {
+		patterns = git.ConfigValues(repositoryPath, "ccg.releaseTags")
+	}
+	if patterns == nil {
+		patterns = c.ReleaseTags
+	}
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, tag := range newTags(versions, next.Refs) {
+		for _, pattern := range patterns {
+			if matchTag(pattern, tag) {
+				log.Printf("archiving release %s", strings.TrimPrefix(tag, "refs/tags/"))
+				next.Release = true
+				return true
+			}
+		}
+	}
+
+	if options.Force {
+		return true
+	}
+
+	log.Printf("no new tag matches %s, not archiving this push", strings.Join(patterns, ", "))
+	return false
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"errors"
+	"ethglobal/pkg/git"
+	"ethglobal/pkg/types"
+	"ethglobal/pkg/utils"
+	"os"
+	"path/filepath"
+)
+
+func (c Controller) remoteBundle(repository string, directory string) (string, error) {
+	bundle := filepath.Join(directory, "remote.bundle")
+	_, err := c.RetrieveColdStorage(repository, bundle, types.VersionSelector{})
+	if err != nil {
+		return "", err
+	}
+
+	if git.IsBundle(bundle) {
+		return bundle, nil
+	}
+
+	repositoryPath := filepath.Join(directory, "repository")
+	err = git.Restore(bundle, repositoryPath)
+	if err != nil {
+		return "", err
+	}
+
+	bundle = filepath.Join(directory, "repository.bundle")
+	return bundle, git.CreateBundle(repositoryPath, bundle, nil, false)
+}
+
+func (c Controller) RemoteRefs(repository string) ([]git.Ref, string, error) {
+	_, versions, err := c.metaData(utils.SHA256(repository))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(versions) == 0 {
+		return nil, "", nil
+	}
+
+	if latest := versions[len(versions)-1]; latest.Refs != nil {
+		return git.RefList(latest.Refs), latest.Head, nil
+	}
+
+	directory, err := os.MkdirTemp("", "ccg-remote-*")
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = os.RemoveAll(directory)
+	}()
+
+	bundle, err := c.remoteBundle(repository, directory)
+	if err != nil {
+		return nil, "", err
+	}
+
+	header, err := git.ReadBundleHeader(bundle)
+	if err != nil {
+		return nil, "", err
+	}
+	return header.Refs, git.Head(header.Refs), nil
+}
+
+func (c Controller) FetchRemote(repository string, gitDirectory string) error {
+	directory, err := os.MkdirTemp("", "ccg-remote-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(directory)
+	}()
+
+	bundle, err := c.remoteBundle(repository, directory)
+	if err != nil {
+		return err
+	}
+
+	return git.Unbundle(gitDirectory, bundle)
+}
+
+func (c Controller) PushRemote(repository string, gitDirectory string, updates []git.RefUpdate) error {
+	refs, head, err := c.RemoteRefs(repository)
+	if err != nil {
+		return err
+	}
+
+	directory, err := os.MkdirTemp("", "ccg-remote-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(directory)
+	}()
+
+	staging := filepath.Join(directory, "staging")
+	oids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		oids = append(oids, ref.Oid)
+	}
+
+	if !git.HasObjects(gitDirectory, oids) {
+		_, err = c.RestoreRepository(repository, staging, types.VersionSelector{})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = git.Stage(gitDirectory, staging, refs, head)
+	if err != nil {
+		return err
+	}
+
+	applied, err := git.Update(gitDirectory, staging, updates)
+	if err != nil || applied == 0 {
+		return err
+	}
+
+	options := types.PushOptions{ReleaseTags: git.ConfigValues(gitDirectory, "ccg.releaseTags")}
+	next := types.VersionMetaData{}
+	err = describeRepository(staging, &next)
+	if err != nil {
+		return err
+	}
+
+	versions, err := c.versions(utils.SHA256(repository))
+	if err != nil {
+		return err
+	}
+
+	if !c.releasePolicy(staging, versions, &next, options) {
+		return errors.New("not archived, no new tag matches the release tag patterns")
+	}
+
+	_, err = c.PushColdStorage(repository, staging, next.Refs["HEAD"], types.PushOptions{Release: next.Release, ReleaseTags: []string{}})
+	return err
+}
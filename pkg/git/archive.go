@@ -0,0 +1,364 @@
+package git
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	FormatBundle  = "bundle"
+	FormatTarball = "tar.gz"
+)
+
+func isRef(name string) bool {
+	return name == "HEAD" || name == "packed-refs" || strings.HasPrefix(name, "refs/")
+}
+
+func Format(archivePath string) (string, error) {
+	if IsBundle(archivePath) {
+		return FormatBundle, nil
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	decompressed, err := gzip.NewReader(file)
+	if err != nil {
+		return "", fmt.Errorf("%s is neither a git bundle nor a .git tarball: %w", archivePath, err)
+	}
+
+	_, err = tar.NewReader(decompressed).Next()
+	if err != nil {
+		return "", fmt.Errorf("%s is neither a git bundle nor a .git tarball: %w", archivePath, err)
+	}
+	return FormatTarball, nil
+}
+
+func fingerprint(entries map[string][]byte) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write(entries[name])
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func RefsFingerprint(refs []Ref) string {
+	entries := map[string][]byte{}
+	for _, ref := range refs {
+		entries[ref.Name] = []byte(ref.Oid)
+	}
+	return fingerprint(entries)
+}
+
+func bundleFingerprint(archivePath string) (string, error) {
+	header, err := ReadBundleHeader(archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	entries := map[string][]byte{}
+	for _, ref := range header.Refs {
+		entries[ref.Name] = []byte(ref.Oid)
+	}
+	for _, prerequisite := range header.Prerequisites {
+		entries["-"+prerequisite] = nil
+	}
+	return fingerprint(entries), nil
+}
+
+func tarballFingerprint(archivePath string) (string, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	decompressed, err := gzip.NewReader(file)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", archivePath, err)
+	}
+
+	refs := map[string][]byte{}
+	archive := tar.NewReader(decompressed)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", archivePath, err)
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), ".git/")
+		if header.Typeflag != tar.TypeReg || !isRef(name) {
+			continue
+		}
+
+		refs[name], err = io.ReadAll(archive)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", archivePath, err)
+		}
+	}
+
+	return fingerprint(refs), nil
+}
+
+func Fingerprint(archivePath string) (string, error) {
+	if IsBundle(archivePath) {
+		return bundleFingerprint(archivePath)
+	}
+	return tarballFingerprint(archivePath)
+}
+
+func archived(name string) bool {
+	switch {
+	case name == "HEAD" || name == "packed-refs" || name == "shallow":
+		return true
+	case strings.HasSuffix(name, ".lock") || strings.Contains(name, "/tmp_"):
+		return false
+	case strings.HasPrefix(name, "refs/"):
+		return true
+	case strings.HasPrefix(name, "objects/pack/"):
+		return strings.HasSuffix(name, ".pack") || strings.HasSuffix(name, ".idx")
+	case strings.HasPrefix(name, "objects/info/"):
+		return false
+	default:
+		return strings.HasPrefix(name, "objects/")
+	}
+}
+
+func CreateTarball(repository string, output string) error {
+	gitDirectory, err := run(repository, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return err
+	}
+	root := strings.TrimSpace(string(gitDirectory))
+
+	if _, err = os.Stat(filepath.Join(root, "objects", "info", "alternates")); err == nil {
+		return fmt.Errorf("%s borrows objects through alternates and cannot be archived as a tarball", repository)
+	}
+
+	var names []string
+	err = filepath.WalkDir(root, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		name, err := filepath.Rel(root, file)
+		if err == nil && archived(filepath.ToSlash(name)) {
+			names = append(names, filepath.ToSlash(name))
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	err = writeTarball(file, root, names)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(output)
+	}
+	return err
+}
+
+func writeTarball(writer io.Writer, root string, names []string) error {
+	compressed, err := gzip.NewWriterLevel(writer, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	archive := tar.NewWriter(compressed)
+
+	directories := map[string]bool{}
+	for _, name := range names {
+		var parents []string
+		for parent := path.Dir(name); !directories[parent]; parent = path.Dir(parent) {
+			directories[parent] = true
+			parents = append([]string{parent}, parents...)
+			if parent == "." {
+				break
+			}
+		}
+
+		for _, parent := range parents {
+			err = archive.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     path.Join(".git", parent) + "/",
+				Mode:     0755,
+				ModTime:  time.Unix(0, 0),
+				Format:   tar.FormatPAX,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = writeEntry(archive, filepath.Join(root, filepath.FromSlash(name)), path.Join(".git", name))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+func writeEntry(archive *tar.Writer, source string, name string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = archive.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     info.Size(),
+		Mode:     0644,
+		ModTime:  time.Unix(0, 0),
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(archive, file, info.Size())
+	return err
+}
+
+func RestoreTarball(archivePath string, target string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	decompressed, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+
+	archive := tar.NewReader(decompressed)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(header.Name)
+		if name != ".git" && !strings.HasPrefix(name, ".git/") {
+			return fmt.Errorf("unexpected archive entry %q", header.Name)
+		}
+		destination := filepath.Join(target, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(destination, 0755)
+		case tar.TypeReg:
+			err = os.MkdirAll(filepath.Dir(destination), 0755)
+			if err == nil {
+				err = writeFile(destination, archive, os.FileMode(header.Mode).Perm())
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err = os.Stat(filepath.Join(target, ".git", "config")); errors.Is(err, os.ErrNotExist) {
+		_, err = run(target, "init", "--quiet")
+		if err != nil {
+			return err
+		}
+	}
+
+	return SyncWorktree(target)
+}
+
+func writeFile(destination string, reader io.Reader, mode os.FileMode) error {
+	file, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func Restore(archivePath string, target string) error {
+	format, err := Format(archivePath)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatBundle:
+		return RestoreBundle(archivePath, target)
+	case FormatTarball:
+		return RestoreTarball(archivePath, target)
+	default:
+		return fmt.Errorf("%s is neither a git bundle nor a .git tarball", archivePath)
+	}
+}
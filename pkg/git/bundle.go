@@ -0,0 +1,447 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+type Ref struct {
+	Name string
+	Oid  string
+}
+
+type BundleHeader struct {
+	Version       int
+	Prerequisites []string
+	Refs          []Ref
+}
+
+func stream(directory string, stdin io.Reader, stdout io.Writer, args ...string) error {
+	command := exec.Command("git", args...)
+	command.Dir = directory
+	command.Stdin = stdin
+	command.Stdout = stdout
+
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+
+	err := command.Run()
+	if err != nil {
+		return fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func run(directory string, args ...string) ([]byte, error) {
+	var output bytes.Buffer
+	err := stream(directory, nil, &output, args...)
+	if err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+func IsBundle(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	signature, err := bufio.NewReader(file).ReadString('\n')
+	return err == nil && (signature == "# v2 git bundle\n" || signature == "# v3 git bundle\n")
+}
+
+func ReadBundleHeader(path string) (BundleHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return BundleHeader{}, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	reader := bufio.NewReader(file)
+	signature, err := reader.ReadString('\n')
+	if err != nil {
+		return BundleHeader{}, fmt.Errorf("%s is not a git bundle", path)
+	}
+
+	var header BundleHeader
+	switch signature {
+	case "# v2 git bundle\n":
+		header.Version = 2
+	case "# v3 git bundle\n":
+		header.Version = 3
+	default:
+		return BundleHeader{}, fmt.Errorf("%s is not a git bundle", path)
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			return BundleHeader{}, fmt.Errorf("%s has a truncated bundle header", path)
+		}
+		if err != nil {
+			return BundleHeader{}, err
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return header, nil
+		case strings.HasPrefix(line, "@"):
+			continue
+		case strings.HasPrefix(line, "-"):
+			oid, _, _ := strings.Cut(line[1:], " ")
+			header.Prerequisites = append(header.Prerequisites, oid)
+		default:
+			oid, name, found := strings.Cut(line, " ")
+			if !found {
+				return BundleHeader{}, fmt.Errorf("%s has an invalid bundle ref %q", path, line)
+			}
+			header.Refs = append(header.Refs, Ref{Name: name, Oid: oid})
+		}
+	}
+}
+
+func Refs(repository string) ([]Ref, error) {
+	output, err := run(repository, "for-each-ref", "--format=%(objectname) %(refname)")
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []Ref
+	head, err := run(repository, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err == nil {
+		refs = append(refs, Ref{Name: "HEAD", Oid: strings.TrimSpace(string(head))})
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		oid, name, found := strings.Cut(line, " ")
+		if found {
+			refs = append(refs, Ref{Name: name, Oid: oid})
+		}
+	}
+	return refs, nil
+}
+
+func RefMap(refs []Ref) map[string]string {
+	if len(refs) == 0 {
+		return nil
+	}
+
+	refMap := make(map[string]string, len(refs))
+	for _, ref := range refs {
+		refMap[ref.Name] = ref.Oid
+	}
+	return refMap
+}
+
+func RefList(refMap map[string]string) []Ref {
+	if len(refMap) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(refMap))
+	for name := range refMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	refs := make([]Ref, 0, len(names))
+	for _, name := range names {
+		refs = append(refs, Ref{Name: name, Oid: refMap[name]})
+	}
+	return refs
+}
+
+func SymbolicHead(repository string) string {
+	output, err := run(repository, "symbolic-ref", "--quiet", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func Peeled(repository string) (map[string]string, error) {
+	output, err := run(repository, "for-each-ref", "--format=%(refname) %(*objectname)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	peeled := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		name, oid, _ := strings.Cut(line, " ")
+		if oid != "" {
+			peeled[name] = oid
+		}
+	}
+
+	if len(peeled) == 0 {
+		return nil, nil
+	}
+	return peeled, nil
+}
+
+func hasObject(repository string, oid string) bool {
+	_, err := run(repository, "cat-file", "-e", oid)
+	return err == nil
+}
+
+func commits(repository string, oids []string) ([]string, error) {
+	var output bytes.Buffer
+	err := stream(repository, revisions(oids, nil), &output, "rev-list", "--no-walk", "--stdin")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output.String()), nil
+}
+
+func revisions(positive []string, negative []string) io.Reader {
+	var input strings.Builder
+	for _, oid := range positive {
+		input.WriteString(oid + "\n")
+	}
+	for _, oid := range negative {
+		input.WriteString("^" + oid + "\n")
+	}
+	return strings.NewReader(input.String())
+}
+
+var reproduciblePack = []string{
+	"-c", "pack.compression=9",
+	"-c", "core.bigFileThreshold=512m",
+	"-c", "pack.useBitmaps=false",
+}
+
+func CreateBundle(repository string, output string, exclude []string, reproducible bool) error {
+	refs, err := Refs(repository)
+	if err != nil {
+		return err
+	}
+
+	if len(refs) == 0 {
+		return fmt.Errorf("%s has no refs to bundle", repository)
+	}
+
+	var positive []string
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		if !seen[ref.Oid] {
+			seen[ref.Oid] = true
+			positive = append(positive, ref.Oid)
+		}
+	}
+
+	var negative []string
+	for _, oid := range exclude {
+		if hasObject(repository, oid) {
+			negative = append(negative, oid)
+		}
+	}
+
+	var prerequisites []string
+	if len(negative) > 0 {
+		var boundary bytes.Buffer
+		err = stream(repository, revisions(positive, negative), &boundary, "rev-list", "--boundary", "--stdin")
+		if err != nil {
+			return err
+		}
+
+		for _, line := range strings.Split(boundary.String(), "\n") {
+			if strings.HasPrefix(line, "-") {
+				prerequisites = append(prerequisites, line[1:])
+			}
+		}
+
+		if len(prerequisites) == 0 {
+			prerequisites, err = commits(repository, negative)
+			if err != nil {
+				return err
+			}
+		}
+		sort.Strings(prerequisites)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	_, _ = writer.WriteString("# v2 git bundle\n")
+	for _, prerequisite := range prerequisites {
+		_, _ = fmt.Fprintf(writer, "-%s\n", prerequisite)
+	}
+	for _, ref := range refs {
+		_, _ = fmt.Fprintf(writer, "%s %s\n", ref.Oid, ref.Name)
+	}
+	_, _ = writer.WriteString("\n")
+
+	args := []string{"pack-objects", "--stdout", "--thin", "--delta-base-offset", "--threads=1", "--revs", "--quiet"}
+	if reproducible {
+		args = append(append(append([]string{}, reproduciblePack...), args...), "--no-reuse-delta", "--no-reuse-object", "--window=10", "--depth=50")
+	}
+
+	err = stream(repository, revisions(positive, negative), writer, args...)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(output)
+	}
+	return err
+}
+
+func VerifyBundle(repository string, bundle string) error {
+	_, err := run(repository, "bundle", "verify", "--quiet", bundle)
+	return err
+}
+
+func Head(refs []Ref) string {
+	var head string
+	for _, ref := range refs {
+		if ref.Name == "HEAD" {
+			head = ref.Oid
+		}
+	}
+
+	var fallback string
+	for _, preferred := range []string{"refs/heads/main", "refs/heads/master"} {
+		for _, ref := range refs {
+			if ref.Name == preferred && (head == "" || ref.Oid == head) {
+				return ref.Name
+			}
+		}
+	}
+
+	for _, ref := range refs {
+		if !strings.HasPrefix(ref.Name, "refs/heads/") {
+			continue
+		}
+		if head == "" || ref.Oid == head {
+			return ref.Name
+		}
+		if fallback == "" {
+			fallback = ref.Name
+		}
+	}
+	return fallback
+}
+
+func setRefs(target string, refs []Ref) error {
+	existing, err := Refs(target)
+	if err != nil {
+		return err
+	}
+
+	wanted := map[string]bool{}
+	for _, ref := range refs {
+		wanted[ref.Name] = true
+		if ref.Name == "HEAD" {
+			continue
+		}
+
+		_, err = run(target, "update-ref", ref.Name, ref.Oid)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, ref := range existing {
+		if ref.Name == "HEAD" || wanted[ref.Name] {
+			continue
+		}
+
+		_, err = run(target, "update-ref", "-d", ref.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func RestoreBundles(bundles []string, refs []Ref, head string, target string) error {
+	if len(bundles) == 0 {
+		return errors.New("no bundles to restore")
+	}
+
+	err := os.MkdirAll(target, 0755)
+	if err != nil {
+		return err
+	}
+
+	_, err = run(target, "init", "--quiet")
+	if err != nil {
+		return err
+	}
+
+	for _, bundle := range bundles {
+		header, err := ReadBundleHeader(bundle)
+		if err != nil {
+			return err
+		}
+
+		err = VerifyBundle(target, bundle)
+		if err != nil {
+			return err
+		}
+
+		_, err = run(target, "-c", "transfer.fsckObjects=true", "fetch", "--quiet", "--update-head-ok", bundle, "+refs/*:refs/*")
+		if err != nil {
+			return err
+		}
+
+		if refs == nil && len(header.Prerequisites) == 0 {
+			refs = header.Refs
+		}
+	}
+
+	if refs == nil {
+		return errors.New("no ref state recorded for incremental bundles")
+	}
+
+	err = setRefs(target, refs)
+	if err != nil {
+		return err
+	}
+
+	recorded := head != ""
+	if !recorded {
+		head = Head(refs)
+	}
+	if head != "" {
+		_, err = run(target, "symbolic-ref", "HEAD", head)
+		if err != nil {
+			return err
+		}
+	}
+
+	oid := RefMap(refs)["HEAD"]
+	if !recorded && oid != "" && resolve(target, "HEAD") != oid {
+		_, err = run(target, "update-ref", "--no-deref", "HEAD", oid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return SyncWorktree(target)
+}
+
+func RestoreBundle(bundle string, target string) error {
+	return RestoreBundles([]string{bundle}, nil, "", target)
+}
@@ -0,0 +1,251 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type RefUpdate struct {
+	Source      string
+	Destination string
+	Force       bool
+	Error       string
+}
+
+func ParseRefspec(refspec string) (RefUpdate, error) {
+	var update RefUpdate
+	if strings.HasPrefix(refspec, "+") {
+		update.Force = true
+		refspec = refspec[1:]
+	}
+
+	source, destination, found := strings.Cut(refspec, ":")
+	if !found || !strings.HasPrefix(destination, "refs/") {
+		return RefUpdate{}, fmt.Errorf("invalid refspec %q", refspec)
+	}
+
+	update.Source = source
+	update.Destination = destination
+	return update, nil
+}
+
+func HasObjects(repository string, oids []string) bool {
+	for _, oid := range oids {
+		if !hasObject(repository, oid) {
+			return false
+		}
+	}
+	return true
+}
+
+func resolve(repository string, revision string) string {
+	output, err := run(repository, "rev-parse", "--verify", "--quiet", revision)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func Stage(local string, target string, refs []Ref, head string) error {
+	err := os.MkdirAll(target, 0755)
+	if err != nil {
+		return err
+	}
+
+	_, err = run(target, "init", "--quiet")
+	if err != nil {
+		return err
+	}
+
+	objects, err := filepath.Abs(filepath.Join(local, "objects"))
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(target, ".git", "objects", "info", "alternates"), []byte(objects+"\n"), 0644)
+	if err != nil {
+		return err
+	}
+
+	if refs == nil {
+		return nil
+	}
+
+	err = setRefs(target, refs)
+	if err != nil {
+		return err
+	}
+
+	if head == "" {
+		head = Head(refs)
+	}
+	if head != "" {
+		_, err = run(target, "symbolic-ref", "HEAD", head)
+	}
+	return err
+}
+
+func Update(local string, target string, updates []RefUpdate) (int, error) {
+	applied := 0
+	for index := range updates {
+		update := &updates[index]
+		old := resolve(target, update.Destination)
+
+		if update.Source == "" {
+			if old == "" {
+				update.Error = "no such ref"
+				continue
+			}
+
+			_, err := run(target, "update-ref", "-d", update.Destination)
+			if err != nil {
+				return applied, err
+			}
+			applied++
+			continue
+		}
+
+		oid := resolve(local, update.Source)
+		if oid == "" {
+			update.Error = fmt.Sprintf("src refspec %s does not match any", update.Source)
+			continue
+		}
+
+		if old != "" && old != oid && !update.Force {
+			if strings.HasPrefix(update.Destination, "refs/tags/") {
+				update.Error = "already exists"
+				continue
+			}
+
+			_, err := run(target, "merge-base", "--is-ancestor", old, oid)
+			if err != nil {
+				update.Error = "non-fast-forward"
+				continue
+			}
+		}
+
+		_, err := run(target, "update-ref", update.Destination, oid)
+		if err != nil {
+			return applied, err
+		}
+		applied++
+	}
+
+	if applied == 0 || resolve(target, "HEAD") != "" {
+		return applied, nil
+	}
+
+	refs, err := Refs(target)
+	if err != nil {
+		return applied, err
+	}
+
+	head := Head(refs)
+	if head == "" {
+		return applied, errors.New("no branch to point HEAD at")
+	}
+
+	_, err = run(target, "symbolic-ref", "HEAD", head)
+	return applied, err
+}
+
+const zeroOid = "0000000000000000000000000000000000000000"
+
+func isAncestor(repository string, ancestor string, descendant string) bool {
+	_, err := run(repository, "merge-base", "--is-ancestor", ancestor, descendant)
+	return err == nil
+}
+
+func AheadOf(repository string, refs map[string]string) bool {
+	if len(refs) == 0 {
+		return false
+	}
+
+	local, err := Refs(repository)
+	if err != nil {
+		return false
+	}
+	current := RefMap(local)
+
+	for name, oid := range refs {
+		old, exists := current[name]
+		switch {
+		case name == "HEAD" || old == oid:
+			continue
+		case !exists || strings.HasPrefix(name, "refs/tags/") || !hasObject(repository, oid) || !isAncestor(repository, oid, old):
+			return false
+		}
+	}
+	return true
+}
+
+func Reconcile(source string, target string) ([]string, error) {
+	sourceRefs, err := Refs(source)
+	if err != nil {
+		return nil, err
+	}
+
+	targetRefs, err := Refs(target)
+	if err != nil {
+		return nil, err
+	}
+	current := RefMap(targetRefs)
+
+	var names []string
+	for _, ref := range sourceRefs {
+		if ref.Name != "HEAD" && current[ref.Name] != ref.Oid {
+			names = append(names, ref.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	absolute, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{"fetch", "--quiet", "--no-tags", "--no-write-fetch-head", absolute}, names...)
+	_, err = run(target, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	head := SymbolicHead(target)
+	var diverged []string
+	var moved bool
+	for _, ref := range sourceRefs {
+		old, exists := current[ref.Name]
+		switch {
+		case ref.Name == "HEAD" || old == ref.Oid:
+			continue
+		case !exists:
+			old = zeroOid
+		case strings.HasPrefix(ref.Name, "refs/tags/") || !isAncestor(target, old, ref.Oid):
+			if !isAncestor(target, ref.Oid, old) {
+				diverged = append(diverged, ref.Name)
+			}
+			continue
+		}
+
+		_, err = run(target, "update-ref", ref.Name, ref.Oid, old)
+		if err != nil {
+			return diverged, err
+		}
+		moved = moved || ref.Name == head
+	}
+
+	if moved {
+		err = SyncWorktree(target)
+	}
+	return diverged, err
+}
+
+func Unbundle(repository string, bundle string) error {
+	_, err := run(repository, "bundle", "unbundle", bundle)
+	return err
+}
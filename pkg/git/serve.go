@@ -0,0 +1,100 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var repositoryName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func RepositoryPath(root string, name string) (string, string, error) {
+	cleaned := strings.Trim(strings.TrimPrefix(name, "~/"), "/")
+	if cleaned == "" {
+		return "", "", errors.New("missing repository name")
+	}
+
+	for _, part := range strings.Split(cleaned, "/") {
+		if part == ".." || !repositoryName.MatchString(part) {
+			return "", "", fmt.Errorf("invalid repository name %q", name)
+		}
+	}
+
+	return cleaned, filepath.Join(root, filepath.FromSlash(cleaned)), nil
+}
+
+func Trust(directory string) {
+	count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	_ = os.Setenv(fmt.Sprintf("GIT_CONFIG_KEY_%d", count), "safe.directory")
+	_ = os.Setenv(fmt.Sprintf("GIT_CONFIG_VALUE_%d", count), directory)
+	_ = os.Setenv("GIT_CONFIG_COUNT", strconv.Itoa(count+1))
+}
+
+func IsRepository(directory string) bool {
+	output, err := run(directory, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return false
+	}
+
+	absolute, err := filepath.Abs(directory)
+	if err == nil {
+		absolute, err = filepath.EvalSymlinks(absolute)
+	}
+	if err != nil {
+		return false
+	}
+
+	gitDirectory := strings.TrimSpace(string(output))
+	return gitDirectory == absolute || gitDirectory == filepath.Join(absolute, ".git")
+}
+
+func ConfigValues(repository string, key string) []string {
+	output, err := run(repository, "config", "--get-all", key)
+	if err != nil {
+		return nil
+	}
+
+	var values []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+	return values
+}
+
+func HeadCommit(repository string) (string, error) {
+	commit := resolve(repository, "HEAD^{commit}")
+	if commit == "" {
+		return "", fmt.Errorf("%s has no commits", repository)
+	}
+	return commit, nil
+}
+
+func SyncWorktree(repository string) error {
+	output, err := run(repository, "rev-parse", "--is-bare-repository")
+	if err != nil || strings.TrimSpace(string(output)) == "true" {
+		return err
+	}
+
+	if resolve(repository, "HEAD^{commit}") == "" {
+		return nil
+	}
+
+	_, err = run(repository, "reset", "--hard", "--quiet", "HEAD")
+	return err
+}
+
+func Serve(service string, repository string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	command := exec.Command("git", service, repository)
+	command.Stdin = stdin
+	command.Stdout = stdout
+	command.Stderr = stderr
+	return command.Run()
+}
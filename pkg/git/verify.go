@@ -0,0 +1,51 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrIntegrity = errors.New("integrity check failed")
+
+func integrity(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrIntegrity, fmt.Sprintf(format, args...))
+}
+
+func Verify(repository string, refs map[string]string, head string, commitHash string) error {
+	_, err := run(repository, "fsck", "--full", "--no-dangling", "--no-progress")
+	if err != nil {
+		return integrity("%v", err)
+	}
+
+	actual, err := Refs(repository)
+	if err != nil {
+		return err
+	}
+
+	if refs != nil {
+		actualMap := RefMap(actual)
+		for _, ref := range RefList(refs) {
+			if actualMap[ref.Name] != ref.Oid {
+				return integrity("ref %s is %q, expected %s", ref.Name, actualMap[ref.Name], ref.Oid)
+			}
+		}
+		for _, ref := range actual {
+			if _, ok := refs[ref.Name]; !ok {
+				return integrity("unexpected ref %s", ref.Name)
+			}
+		}
+	}
+
+	if head != "" && SymbolicHead(repository) != head {
+		return integrity("HEAD does not point at %s", head)
+	}
+
+	if commitHash != "" {
+		commit := resolve(repository, "HEAD^{commit}")
+		if commit != commitHash {
+			return integrity("HEAD is at %q, expected commit %s", commit, commitHash)
+		}
+	}
+
+	return nil
+}
@@ -2,15 +2,29 @@ package lighthouse
 
 import (
 	"ethglobal/pkg/types"
-	"net/http"
+	"ethglobal/pkg/utils"
 )
 
 func InitLightHouseClient(configuration types.Configuration) *types.LighthouseClient {
+	gateways := configuration.Gateways
+	if len(gateways) == 0 {
+		gateways = []types.Gateway{{
+			Url:     "https://gateway.lighthouse.storage",
+			Timeout: configuration.ConnectionTimeout,
+		}}
+	}
+
+	client := utils.NewStreamingClient(configuration.ConnectionTimeout)
 	return &types.LighthouseClient{
+		ApiUrl:      configuration.LighthouseApiUrl,
+		UploadUrl:   configuration.LighthouseUploadUrl,
 		ApiKey:      configuration.LighthouseKey,
 		ApiKeyBytes: []byte(configuration.LighthouseKey),
-		Client: &http.Client{
-			Timeout: configuration.ConnectionTimeout,
+		Client:      client,
+		Gateways: &types.GatewayPool{
+			Gateways: gateways,
+			Race:     configuration.GatewayRace,
+			Client:   client,
 		},
 	}
 }
@@ -0,0 +1,140 @@
+package podsi
+
+import (
+	"errors"
+
+	"github.com/ipfs/go-cid"
+)
+
+const (
+	unpaddedChunk = 127
+	paddedChunk   = 128
+)
+
+type Piece struct {
+	Cid  cid.Cid
+	Size uint64
+}
+
+type Calculator struct {
+	buffer []byte
+	levels []*Node
+	leaves uint64
+	size   uint64
+}
+
+func NewCalculator() *Calculator {
+	return &Calculator{buffer: make([]byte, 0, unpaddedChunk)}
+}
+
+func zeroCommitment(level int) Node {
+	var node Node
+	for ; level > 0; level-- {
+		node = computeNode(node, node)
+	}
+	return node
+}
+
+func fr32Pad(in []byte) []byte {
+	out := make([]byte, paddedChunk)
+	copy(out, in[:31])
+
+	t := in[31] >> 6
+	out[31] = in[31] & 0x3f
+	var v byte
+
+	for i := 32; i < 64; i++ {
+		v = in[i]
+		out[i] = (v << 2) | t
+		t = v >> 6
+	}
+
+	t = v >> 4
+	out[63] &= 0x3f
+
+	for i := 64; i < 96; i++ {
+		v = in[i]
+		out[i] = (v << 4) | t
+		t = v >> 4
+	}
+
+	t = v >> 2
+	out[95] &= 0x3f
+
+	for i := 96; i < 127; i++ {
+		v = in[i]
+		out[i] = (v << 6) | t
+		t = v >> 2
+	}
+
+	out[127] = t & 0x3f
+	return out
+}
+
+func (c *Calculator) add(node Node, level int) {
+	for ; level < len(c.levels) && c.levels[level] != nil; level++ {
+		node = computeNode(*c.levels[level], node)
+		c.levels[level] = nil
+	}
+	if level == len(c.levels) {
+		c.levels = append(c.levels, nil)
+	}
+	c.levels[level] = &node
+}
+
+func (c *Calculator) chunk(data []byte) {
+	padded := fr32Pad(data)
+	for offset := 0; offset < paddedChunk; offset += NodeSize {
+		c.add(Node(padded[offset:offset+NodeSize]), 0)
+		c.leaves++
+	}
+}
+
+func (c *Calculator) Write(p []byte) (int, error) {
+	written := len(p)
+	c.size += uint64(written)
+
+	if len(c.buffer) > 0 {
+		n := min(unpaddedChunk-len(c.buffer), len(p))
+		c.buffer = append(c.buffer, p[:n]...)
+		p = p[n:]
+		if len(c.buffer) < unpaddedChunk {
+			return written, nil
+		}
+		c.chunk(c.buffer)
+		c.buffer = c.buffer[:0]
+	}
+
+	for ; len(p) >= unpaddedChunk; p = p[unpaddedChunk:] {
+		c.chunk(p[:unpaddedChunk])
+	}
+	c.buffer = append(c.buffer, p...)
+
+	return written, nil
+}
+
+func (c *Calculator) Sum() (Piece, error) {
+	if c.size == 0 {
+		return Piece{}, errors.New("can't compute the piece commitment of empty data")
+	}
+
+	if len(c.buffer) > 0 {
+		c.chunk(append(c.buffer, make([]byte, unpaddedChunk-len(c.buffer))...))
+		c.buffer = c.buffer[:0]
+	}
+
+	top := log2Ceil(c.leaves)
+	for level := 0; level < top; level++ {
+		if level < len(c.levels) && c.levels[level] != nil {
+			node := *c.levels[level]
+			c.levels[level] = nil
+			c.add(computeNode(node, zeroCommitment(level)), level+1)
+		}
+	}
+
+	piece, err := PieceCid(*c.levels[top])
+	if err != nil {
+		return Piece{}, err
+	}
+	return Piece{Cid: piece, Size: NodeSize << top}, nil
+}
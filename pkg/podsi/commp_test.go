@@ -0,0 +1,65 @@
+package podsi
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func random(size int) []byte {
+	source := rand.New(rand.NewSource(1337))
+	data := make([]byte, size)
+	for i := 0; i < size; {
+		n := source.Uint32()
+		for j := 0; j < 4 && i < size; j++ {
+			data[i] = byte(n)
+			n >>= 8
+			i++
+		}
+	}
+	return data
+}
+
+// Piece CIDs from the Lotus-generated vectors in go-fil-commp-hashhash v0.2.0
+// (testdata/zero.txt, 0xCC.txt and random.txt, seed 1337).
+var knownPieces = []struct {
+	name string
+	data []byte
+	size uint64
+	cid  string
+}{
+	{"zero 128 B", make([]byte, 127), 128, "baga6ea4seaqdomn3tgwgrh3g532zopskstnbrd2n3sxfqbze7rxt7vqn7veigmy"},
+	{"zero 2 KiB", make([]byte, 2032), 2048, "baga6ea4seaqpy7usqklokfx2vxuynmupslkeutzexe2uqurdg5vhtebhxqmpqmy"},
+	{"zero padded", make([]byte, 96), 128, "baga6ea4seaqdomn3tgwgrh3g532zopskstnbrd2n3sxfqbze7rxt7vqn7veigmy"},
+	{"0xCC 127 B", bytes.Repeat([]byte{0xcc}, 127), 128, "baga6ea4seaqmfldjtozgne6adk7eve2vdxte7vzlivae7nzsbrawobo546zkijq"},
+	{"0xCC 1017 B", bytes.Repeat([]byte{0xcc}, 1017), 2048, "baga6ea4seaqf3n5ob5qonkwnxfcbjzftsagbnrjfzualqvzhcylz46b7sgz6wmi"},
+	{"random 1016 B", random(1016), 1024, "baga6ea4seaqnvlx4oaqkcjrog6v27jpouto6rbsymqvljftyxitm7jfalzpmwcq"},
+	{"random 65025 B", random(65025), 131072, "baga6ea4seaqapw7q3joywtwplratzjnyzxfsi5nuemcgtyqapmd35sdyjtnfkpa"},
+	{"random 1040384 B", random(1040384), 1048576, "baga6ea4seaqio7pi5twddpb4qevcestrwtc77nuou7o2xilhkhkj46irbsolaoq"},
+}
+
+func TestCommP(t *testing.T) {
+	for _, known := range knownPieces {
+		for _, write := range []int{len(known.data), 127, 1000} {
+			calculator := NewCalculator()
+			for offset := 0; offset < len(known.data); offset += write {
+				_, _ = calculator.Write(known.data[offset:min(offset+write, len(known.data))])
+			}
+
+			piece, err := calculator.Sum()
+			if err != nil {
+				t.Fatalf("%s: %v", known.name, err)
+			}
+			if piece.Cid.String() != known.cid || piece.Size != known.size {
+				t.Fatalf("%s: piece is %s (%d bytes), expected %s (%d bytes)", known.name, piece.Cid, piece.Size, known.cid, known.size)
+			}
+		}
+	}
+}
+
+func TestCommPEmpty(t *testing.T) {
+	_, err := NewCalculator().Sum()
+	if err == nil {
+		t.Fatal("empty data has a piece commitment")
+	}
+}
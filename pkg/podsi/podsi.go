@@ -0,0 +1,203 @@
+package podsi
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+const (
+	NodeSize     = 32
+	ChecksumSize = 16
+	EntrySize    = NodeSize + 2*8 + ChecksumSize
+
+	fr32Sha256Trunc254Padbintree = 0x1011
+)
+
+type Node [NodeSize]byte
+
+type ProofData struct {
+	Index uint64
+	Path  []Node
+}
+
+type InclusionProof struct {
+	ProofSubtree ProofData
+	ProofIndex   ProofData
+}
+
+type VerifierData struct {
+	CommPc cid.Cid
+	SizePc uint64
+}
+
+type AuxData struct {
+	CommPa cid.Cid
+	SizePa uint64
+}
+
+func truncatedHash(data []byte) Node {
+	node := Node(sha256.Sum256(data))
+	node[NodeSize-1] &= 0b00111111
+	return node
+}
+
+func computeNode(left Node, right Node) Node {
+	return truncatedHash(append(left[:], right[:]...))
+}
+
+func (pd ProofData) ComputeRoot(subtree Node) (Node, error) {
+	if len(pd.Path) > 63 {
+		return Node{}, errors.New("merkle proofs deeper than 63 are not supported")
+	}
+	if pd.Index>>len(pd.Path) != 0 {
+		return Node{}, errors.New("proof index greater than width of the tree")
+	}
+
+	carry := subtree
+	index := pd.Index
+	for _, sibling := range pd.Path {
+		if index&1 == 1 {
+			carry = computeNode(sibling, carry)
+		} else {
+			carry = computeNode(carry, sibling)
+		}
+		index >>= 1
+	}
+
+	return carry, nil
+}
+
+func PieceCommitment(c cid.Cid) (Node, error) {
+	decoded, err := multihash.Decode(c.Hash())
+	if err != nil {
+		return Node{}, err
+	}
+
+	switch {
+	case c.Type() == cid.FilCommitmentUnsealed && decoded.Code == multihash.SHA2_256_TRUNC254_PADDED:
+		if len(decoded.Digest) != NodeSize {
+			return Node{}, fmt.Errorf("invalid piece commitment length %d", len(decoded.Digest))
+		}
+		return Node(decoded.Digest), nil
+	case c.Type() == cid.Raw && decoded.Code == fr32Sha256Trunc254Padbintree:
+		_, n := binary.Uvarint(decoded.Digest)
+		if n <= 0 || len(decoded.Digest) != n+1+NodeSize {
+			return Node{}, fmt.Errorf("invalid piece commitment length %d", len(decoded.Digest))
+		}
+		return Node(decoded.Digest[n+1:]), nil
+	default:
+		return Node{}, fmt.Errorf("%s is not a piece cid", c)
+	}
+}
+
+func PieceCid(commP Node) (cid.Cid, error) {
+	hash, err := multihash.Encode(commP[:], multihash.SHA2_256_TRUNC254_PADDED)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return cid.NewCidV1(cid.FilCommitmentUnsealed, hash), nil
+}
+
+func segmentEntry(commDs Node, offset uint64, size uint64) []byte {
+	entry := make([]byte, EntrySize)
+	copy(entry, commDs[:])
+	binary.LittleEndian.PutUint64(entry[NodeSize:], offset)
+	binary.LittleEndian.PutUint64(entry[NodeSize+8:], size)
+
+	checksum := sha256.Sum256(entry)
+	copy(entry[NodeSize+16:], checksum[:ChecksumSize])
+	entry[EntrySize-1] &= 0b00111111
+	return entry
+}
+
+func log2Ceil(value uint64) int {
+	if value <= 1 {
+		return 0
+	}
+	return bits.Len64(value - 1)
+}
+
+func maxIndexEntries(sizePa uint64) uint64 {
+	entries := uint64(1) << log2Ceil(sizePa/2048/EntrySize)
+	if entries < 4 {
+		return 4
+	}
+	return entries
+}
+
+func (ip InclusionProof) ComputeExpectedAuxData(verifier VerifierData) (AuxData, error) {
+	if verifier.SizePc == 0 || verifier.SizePc&(verifier.SizePc-1) != 0 {
+		return AuxData{}, errors.New("piece size is not a power of two")
+	}
+
+	commPc, err := PieceCommitment(verifier.CommPc)
+	if err != nil {
+		return AuxData{}, err
+	}
+
+	commPa, err := ip.ProofSubtree.ComputeRoot(commPc)
+	if err != nil {
+		return AuxData{}, fmt.Errorf("invalid subtree proof: %v", err)
+	}
+
+	depth := len(ip.ProofSubtree.Path)
+	if bits.LeadingZeros64(verifier.SizePc) <= depth {
+		return AuxData{}, errors.New("aggregate size overflows")
+	}
+	sizePa := verifier.SizePc << depth
+
+	entry := segmentEntry(commPc, ip.ProofSubtree.Index*verifier.SizePc, verifier.SizePc)
+	commPa2, err := ip.ProofIndex.ComputeRoot(truncatedHash(entry))
+	if err != nil {
+		return AuxData{}, fmt.Errorf("invalid index proof: %v", err)
+	}
+
+	if commPa != commPa2 {
+		return AuxData{}, fmt.Errorf("aggregate commitments don't match: %x != %x", commPa, commPa2)
+	}
+
+	if len(ip.ProofIndex.Path) > 58 || uint64(EntrySize)<<len(ip.ProofIndex.Path) != sizePa {
+		return AuxData{}, errors.New("aggregate sizes don't match")
+	}
+
+	indexStart := sizePa - maxIndexEntries(sizePa)*EntrySize
+	if ip.ProofIndex.Index*EntrySize < indexStart {
+		return AuxData{}, fmt.Errorf("index entry at wrong position: %d < %d", ip.ProofIndex.Index*EntrySize, indexStart)
+	}
+
+	pieceCid, err := PieceCid(commPa)
+	if err != nil {
+		return AuxData{}, err
+	}
+
+	return AuxData{CommPa: pieceCid, SizePa: sizePa}, nil
+}
+
+func Verify(proof InclusionProof, verifier VerifierData, aggregate cid.Cid) (AuxData, error) {
+	aux, err := proof.ComputeExpectedAuxData(verifier)
+	if err != nil {
+		return AuxData{}, err
+	}
+
+	commPa, err := PieceCommitment(aggregate)
+	if err != nil {
+		return AuxData{}, err
+	}
+
+	expected, err := PieceCommitment(aux.CommPa)
+	if err != nil {
+		return AuxData{}, err
+	}
+
+	if commPa != expected {
+		return AuxData{}, fmt.Errorf("proof leads to aggregate %s, not %s", aux.CommPa, aggregate)
+	}
+
+	return aux, nil
+}
@@ -0,0 +1,146 @@
+package podsi
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func node(t *testing.T, text string) Node {
+	decoded, err := hex.DecodeString(text)
+	if err != nil || len(decoded) != NodeSize {
+		t.Fatalf("invalid node %q", text)
+	}
+	return Node(decoded)
+}
+
+func pieceCid(t *testing.T, text string) cid.Cid {
+	decoded, err := hex.DecodeString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := cid.Cast(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// InclusionGolden1 from go-data-segment v0.0.1 (datasegment/incusion_golden_test.go).
+func golden(t *testing.T) (InclusionProof, VerifierData, AuxData) {
+	proof := InclusionProof{
+		ProofSubtree: ProofData{Index: 0x5},
+		ProofIndex:   ProofData{Index: 0x1ffc0003},
+	}
+	for _, text := range []string{
+		"0d0e0a0100020000000000000000000000000000000000000000000000000000",
+		"0d0e0a0100040000000000000000000000000000000000000000000000000000",
+		"b6a5c5d0cbaabd7e63de256c819d84623fde6f53d616120508667b12659f7c3e",
+		"2df9cf74cb24e6349b809399b3a046640219dce8b97954eec43bf605dcc59b2d",
+		"d8610218425ab5e95b1ca6239d29a2e420d706a96f373e2f9c9a91d759d19b01",
+		"d628c4e101d5ca9aa4b341e4d0f028be8636fd7a0c3bf691cef16113b8d97932",
+	} {
+		proof.ProofSubtree.Path = append(proof.ProofSubtree.Path, node(t, text))
+	}
+	for _, text := range []string{
+		"ca99a41370d2dd04f7d97b0fed8a9833031291a6f7c825d7245b428fef8b2734",
+		"2bc4f6cafd6a8366d032dfc7fceefd0ff2fb34dd2ea910da454773057333dd2a",
+		"578b81a6596624f326b1d31e2e3db91062545d2f819d605cc4afef3377151800",
+		"0e067c9486c9d41ff6cfeaf2d4b330d432e6aefa18eacbb5ce072ca197760215",
+		"1f7ac9595510e09ea41c460b176430bb322cd6fb412ec57cb17d989a4310372f",
+		"fc7e928296e516faade986b28f92d44a4f24b935485223376a799027bc18f833",
+		"08c47b38ee13bc43f41b915c0eed9911a26086b3ed62401bf9d58b8d19dff624",
+		"b2e47bfb11facd941f62af5c750f3ea5cc4df517d5c4f16db2b4d77baec1a32f",
+		"f9226160c8f927bfdcc418cdf203493146008eaefb7d02194d5e548189005108",
+		"2c1a964bb90b59ebfe0f6da29ad65ae3e417724a8f7c11745a40cac1e5e74011",
+		"fee378cef16404b199ede0b13e11b624ff9d784fbbed878d83297e795e024f02",
+		"8e9e2403fa884cf6237f60df25f83ee40dca9ed879eb6f6352d15084f5ad0d3f",
+		"752d9693fa167524395476e317a98580f00947afb7a30540d625a9291cc12a07",
+		"7022f60f7ef6adfa17117a52619e30cea82c68075adf1c667786ec506eef2d19",
+		"d99887b973573a96e11393645236c17b1f4c7034d723c7a99f709bb4da61162b",
+		"d0b530dbb0b4f25c5d2f2a28dfee808b53412a02931f18c499f5a254086b1326",
+		"84c0421ba0685a01bf795a2344064fe424bd52a9d24377b394ff4c4b4568e811",
+		"65f29e5d98d246c38b388cfc06db1f6b021303c5a289000bdce832a9c3ec421c",
+		"a2247508285850965b7e334b3127b0c042b1d046dc54402137627cd8799ce13a",
+		"dafdab6da9364453c26d33726b9fefe343be8f81649ec009aad3faff50617508",
+		"d941d5e0d6314a995c33ffbd4fbe69118d73d4e5fd2cd31f0f7c86ebdd14e706",
+		"514c435c3d04d349a5365fbd59ffc713629111785991c1a3c53af22079741a2f",
+		"ad06853969d37d34ff08e09f56930a4ad19a89def60cbfee7e1d3381c1e71c37",
+		"39560e7b13a93b07a243fd2720ffa7cb3e1d2e505ab3629e79f46313512cda06",
+		"ccc3c012f5b05e811a2bbfdd0f6833b84275b47bf229c0052a82484f3c1a5b3d",
+		"7df29b69773199e8f2b40b77919d048509eed768e2c7297b1f1437034fc3c62c",
+		"66ce05a3667552cf45c02bcc4e8392919bdeac35de2ff56271848e9f7b675107",
+		"d8610218425ab5e95b1ca6239d29a2e420d706a96f373e2f9c9a91d759d19b01",
+		"d0eef6d1bccabc5b5b9e3af2fea8ea9d184f08f43ac2071bdc635d44bbe35115",
+	} {
+		proof.ProofIndex.Path = append(proof.ProofIndex.Path, node(t, text))
+	}
+
+	verifier := VerifierData{
+		CommPc: pieceCid(t, "0181e2039220200d0e0a0100030000000000000000000000000000000000000000000000000000"),
+		SizePc: 0x20000000,
+	}
+	aux := AuxData{
+		CommPa: pieceCid(t, "0181e2039220203f46bc645b07a3ea2c04f066f939ddf7e269dd77671f9e1e61a3a3797e665127"),
+		SizePa: 0x800000000,
+	}
+	return proof, verifier, aux
+}
+
+func TestVerifyGolden(t *testing.T) {
+	proof, verifier, expected := golden(t)
+
+	aux, err := Verify(proof, verifier, expected.CommPa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !aux.CommPa.Equals(expected.CommPa) || aux.SizePa != expected.SizePa {
+		t.Fatalf("aggregate is %s (%d bytes), expected %s (%d bytes)", aux.CommPa, aux.SizePa, expected.CommPa, expected.SizePa)
+	}
+}
+
+func TestVerifyRejectsTamperedProof(t *testing.T) {
+	for name, tamper := range map[string]func(proof *InclusionProof, verifier *VerifierData){
+		"subtree node": func(proof *InclusionProof, verifier *VerifierData) {
+			proof.ProofSubtree.Path[2][0] ^= 1
+		},
+		"subtree index": func(proof *InclusionProof, verifier *VerifierData) {
+			proof.ProofSubtree.Index = 4
+		},
+		"index node": func(proof *InclusionProof, verifier *VerifierData) {
+			proof.ProofIndex.Path[0][5] ^= 1
+		},
+		"index position": func(proof *InclusionProof, verifier *VerifierData) {
+			proof.ProofIndex.Index = 3
+		},
+		"piece size": func(proof *InclusionProof, verifier *VerifierData) {
+			verifier.SizePc = 0x10000000
+		},
+		"piece": func(proof *InclusionProof, verifier *VerifierData) {
+			verifier.CommPc = pieceCid(t, "0181e2039220200d0e0a0100030000000000000000000000000000000000000000000000000001")
+		},
+	} {
+		proof, verifier, expected := golden(t)
+		tamper(&proof, &verifier)
+
+		_, err := Verify(proof, verifier, expected.CommPa)
+		if err == nil {
+			t.Fatalf("%s: tampered proof was accepted", name)
+		}
+	}
+}
+
+func TestVerifyRejectsOtherAggregate(t *testing.T) {
+	proof, verifier, _ := golden(t)
+	other, err := cid.Decode("baga6ea4seaqdomn3tgwgrh3g532zopskstnbrd2n3sxfqbze7rxt7vqn7veigmy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Verify(proof, verifier, other)
+	if err == nil || !strings.Contains(err.Error(), "proof leads to aggregate") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
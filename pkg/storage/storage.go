@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"errors"
+	"ethglobal/pkg/lighthouse"
+	"ethglobal/pkg/types"
+	"ethglobal/pkg/utils"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func InitLocalStorage(configuration types.Configuration) (*types.LocalStorage, error) {
+	if configuration.LocalStorageDirectory == "" {
+		return nil, errors.New("LOCAL_STORAGE_DIRECTORY is not set")
+	}
+
+	err := os.MkdirAll(configuration.LocalStorageDirectory, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.LocalStorage{
+		Directory: configuration.LocalStorageDirectory,
+	}, nil
+}
+
+func InitKuboClient(configuration types.Configuration) (*types.KuboClient, error) {
+	if configuration.KuboApiUrl == "" {
+		return nil, errors.New("KUBO_API_URL is not set")
+	}
+
+	return &types.KuboClient{
+		ApiUrl:        configuration.KuboApiUrl,
+		Authorization: configuration.KuboAuthorization,
+		Client:        utils.NewStreamingClient(configuration.ConnectionTimeout),
+	}, nil
+}
+
+func InitS3Client(configuration types.Configuration) (*types.S3Client, error) {
+	if configuration.S3Endpoint == "" || configuration.S3Bucket == "" {
+		return nil, errors.New("S3_ENDPOINT and S3_BUCKET must be set")
+	}
+
+	region := configuration.S3Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &types.S3Client{
+		Endpoint:  configuration.S3Endpoint,
+		Bucket:    configuration.S3Bucket,
+		Region:    region,
+		Prefix:    configuration.S3Prefix,
+		AccessKey: configuration.S3AccessKey,
+		SecretKey: configuration.S3SecretKey,
+		Client:    utils.NewStreamingClient(configuration.ConnectionTimeout),
+	}, nil
+}
+
+func InitReplicatedStorage(configuration types.Configuration, names []string) (*types.ReplicatedStorage, error) {
+	replicated := &types.ReplicatedStorage{
+		MinReplicas: configuration.StorageMinReplicas,
+		Race:        configuration.StorageRace,
+	}
+
+	for _, name := range names {
+		backend, err := initBackend(configuration, strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+
+		if replicated.Backend(backend.Name()) != nil {
+			return nil, fmt.Errorf("storage backend %q listed twice", backend.Name())
+		}
+		replicated.Backends = append(replicated.Backends, backend)
+	}
+
+	if replicated.MinReplicas <= 0 || replicated.MinReplicas > len(replicated.Backends) {
+		replicated.MinReplicas = len(replicated.Backends)
+	}
+
+	return replicated, nil
+}
+
+func InitStorageBackend(configuration types.Configuration) (types.StorageBackend, error) {
+	names := strings.Split(configuration.StorageBackend, ",")
+	if len(names) > 1 {
+		return InitReplicatedStorage(configuration, names)
+	}
+
+	return initBackend(configuration, strings.TrimSpace(names[0]))
+}
+
+func initBackend(configuration types.Configuration, name string) (types.StorageBackend, error) {
+	switch name {
+	case "", "lighthouse":
+		return lighthouse.InitLightHouseClient(configuration), nil
+	case "local":
+		return InitLocalStorage(configuration)
+	case "kubo":
+		return InitKuboClient(configuration)
+	case "s3":
+		return InitS3Client(configuration)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", name)
+	}
+}
@@ -3,13 +3,37 @@ package types
 import (
 	"context"
 	"ethglobal/pkg/abi"
+	"fmt"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"math/big"
 	"time"
 )
 
+type MarketDeal struct {
+	Proposal struct {
+		PieceCid struct {
+			Root string `json:"/"`
+		} `json:"PieceCID"`
+		PieceSize uint64 `json:"PieceSize"`
+		Provider  string `json:"Provider"`
+	} `json:"Proposal"`
+	State struct {
+		SectorStartEpoch int64 `json:"SectorStartEpoch"`
+		SlashEpoch       int64 `json:"SlashEpoch"`
+	} `json:"State"`
+}
+
+type ProjectUpdate struct {
+	Cid         []byte
+	MetaData    []byte
+	Transaction string
+	Block       uint64
+}
+
 type ContractActions struct {
 	Chain      *big.Int
 	GetTimeout time.Duration
@@ -19,6 +43,8 @@ type ContractActions struct {
 	Keystore *keystore.KeyStore
 
 	Contract    *abi.Abi
+	Client      *ethclient.Client
+	Updates     bool
 	RootContext context.Context
 }
 
@@ -76,3 +102,71 @@ func (c *ContractActions) SetProject(repositoryIdentifier [32]byte, cid []byte,
 
 	return tx.Hash().Hex(), nil
 }
+
+func (c *ContractActions) Receipt(transaction string) (uint64, int64, error) {
+	ctx, cancel := context.WithTimeout(c.RootContext, c.GetTimeout)
+	defer cancel()
+
+	receipt, err := c.Client.TransactionReceipt(ctx, common.HexToHash(transaction))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	header, err := c.Client.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return 0, 0, err
+	}
+	return receipt.BlockNumber.Uint64(), int64(header.Time), nil
+}
+
+func (c *ContractActions) MarketDeal(dealId int64) (MarketDeal, error) {
+	ctx, cancel := context.WithTimeout(c.RootContext, c.GetTimeout)
+	defer cancel()
+
+	var deal MarketDeal
+	err := c.Client.Client().CallContext(ctx, &deal, "Filecoin.StateMarketStorageDeal", dealId, nil)
+	if err != nil {
+		return MarketDeal{}, fmt.Errorf("failed to look up deal %d: %v", dealId, err)
+	}
+	return deal, nil
+}
+
+func (c *ContractActions) ProjectUpdates(repositoryIdentifier [32]byte, start uint64, end uint64) ([]ProjectUpdate, error) {
+	ctx, cancel := context.WithTimeout(c.RootContext, c.GetTimeout)
+	defer cancel()
+
+	head, err := c.Client.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if start > head {
+		return nil, nil
+	}
+	end = min(end, head)
+
+	iterator, err := c.Contract.FilterProjectUpdated(
+		&bind.FilterOpts{
+			Start:   start,
+			End:     &end,
+			Context: ctx,
+		},
+		[][32]byte{repositoryIdentifier},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = iterator.Close()
+	}()
+
+	var updates []ProjectUpdate
+	for iterator.Next() {
+		updates = append(updates, ProjectUpdate{
+			Cid:         iterator.Event.Cid,
+			MetaData:    iterator.Event.MetaData,
+			Transaction: iterator.Event.Raw.TxHash.Hex(),
+			Block:       iterator.Event.Raw.BlockNumber,
+		})
+	}
+	return updates, iterator.Error()
+}
@@ -6,13 +6,43 @@ import (
 )
 
 type Configuration struct {
-	GetSeconds        time.Duration
-	SetMinutes        time.Duration
-	LighthouseKey     string
-	ConnectionTimeout time.Duration
-	JsonRPC           string
-	Chain             *big.Int
-	ContactAddress    string
-	KeystoreDirectory string
-	EncryptionKey     string
+	GetSeconds          time.Duration
+	SetMinutes          time.Duration
+	LighthouseKey       string
+	LighthouseApiUrl    string
+	LighthouseUploadUrl string
+	ConnectionTimeout   time.Duration
+	JsonRPC             string
+	Chain               *big.Int
+	ContactAddress      string
+	KeystoreDirectory   string
+	EncryptionKey       string
+
+	StorageBackend        string
+	StorageMinReplicas    int
+	StorageRace           bool
+	LocalStorageDirectory string
+	KuboApiUrl            string
+	KuboAuthorization     string
+	S3Endpoint            string
+	S3Bucket              string
+	S3Region              string
+	S3Prefix              string
+	S3AccessKey           string
+	S3SecretKey           string
+	Gateways              []Gateway
+	GatewayRace           bool
+
+	ChunkSize        int64
+	ChunkRetries     int
+	JournalDirectory string
+	LedgerDirectory  string
+
+	CarUploads   bool
+	CarDirectory string
+
+	ReleaseTags []string
+
+	ReproducibleArchives bool
+	ConvergentEncryption bool
 }
@@ -0,0 +1,87 @@
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+type JsonInt int64
+
+func (i *JsonInt) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	if len(data) == 0 || string(data) == "null" {
+		*i = 0
+		return nil
+	}
+
+	value, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*i = JsonInt(value)
+	return nil
+}
+
+type Deal struct {
+	ChainDealId     JsonInt `json:"chainDealID"`
+	DealId          JsonInt `json:"dealId"`
+	DealUuid        string  `json:"dealUUID"`
+	StorageProvider string  `json:"storageProvider"`
+	Miner           string  `json:"miner"`
+	DealStatus      string  `json:"dealStatus"`
+	StartEpoch      JsonInt `json:"startEpoch"`
+	EndEpoch        JsonInt `json:"endEpoch"`
+	PieceCid        string  `json:"pieceCID"`
+	PieceSize       JsonInt `json:"pieceSize"`
+	PayloadCid      string  `json:"payloadCid"`
+	AggregateIn     string  `json:"aggregateIn"`
+}
+
+type CidDeals struct {
+	Label string `json:"label"`
+	Cid   string `json:"cid"`
+	Deals []Deal `json:"deals"`
+	Error string `json:"error,omitempty"`
+}
+
+func (d Deal) Provider() string {
+	if d.StorageProvider != "" {
+		return d.StorageProvider
+	}
+	return d.Miner
+}
+
+func (d Deal) Id() int64 {
+	if d.ChainDealId != 0 {
+		return int64(d.ChainDealId)
+	}
+	return int64(d.DealId)
+}
+
+func (cd CidDeals) Replication() int {
+	providers := map[string]bool{}
+	for _, deal := range cd.Deals {
+		if deal.Id() != 0 {
+			providers[deal.Provider()] = true
+		}
+	}
+	return len(providers)
+}
+
+func genesis(chain *big.Int) int64 {
+	if chain != nil && chain.Int64() == 314159 {
+		return 1667326380
+	}
+	return 1598306400
+}
+
+func EpochTime(chain *big.Int, epoch int64) time.Time {
+	return time.Unix(genesis(chain)+epoch*30, 0).UTC()
+}
+
+func TimeEpoch(chain *big.Int, timestamp int64) int64 {
+	return max(timestamp-genesis(chain), 0) / 30
+}
@@ -0,0 +1,12 @@
+package types
+
+import "strings"
+
+var archivePayload = strings.Repeat("encrypted archive ", 100000)
+
+// CIDs printed by `ipfs add --only-hash` (kubo v0.32.1, default chunker, CIDv0).
+var addedCids = map[string]string{
+	archivePayload:     "QmW8zFxKfQd8UtVpLgKbPu6rJu7wfRQuEZ8d9Rn5b7tJc2",
+	"chunk contents":   "QmWogk4eEer56VUBbDjUDhZNsRYr94hkFKrkz6Riuce9i2",
+	"original content": "QmRUSJkCj8UCCwkvG715Xg4ppG1d7HiycumyYNP4TFYqnK",
+}
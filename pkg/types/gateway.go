@@ -0,0 +1,217 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"ethglobal/pkg/unixfs"
+	"ethglobal/pkg/utils"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Gateway struct {
+	Url     string
+	Timeout time.Duration
+}
+
+type GatewayPool struct {
+	Gateways []Gateway
+	Race     bool
+	Client   *http.Client
+}
+
+type gatewayBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (gb *gatewayBody) Close() error {
+	err := gb.ReadCloser.Close()
+	gb.cancel()
+	return err
+}
+
+func (gp *GatewayPool) open(ctx context.Context, gateway Gateway, cid string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	endpoint := fmt.Sprintf("%s/ipfs/%s", strings.TrimRight(gateway.Url, "/"), cid)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	timedOut := func() bool { return false }
+	if gateway.Timeout > 0 {
+		timer := time.AfterFunc(gateway.Timeout, cancel)
+		timedOut = func() bool { return !timer.Stop() }
+	}
+
+	resp, err := gp.Client.Do(req)
+	if timedOut() {
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		cancel()
+		return nil, fmt.Errorf("%s: timed out after %v", gateway.Url, gateway.Timeout)
+	}
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("%s: %v", gateway.Url, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("%s: %s %s", gateway.Url, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	body := utils.NewIdleReader(resp.Body, gateway.Timeout)
+	verified, err := unixfs.NewVerifyingReader(body, cid)
+	if err != nil {
+		_ = body.Close()
+		cancel()
+		return nil, err
+	}
+
+	return &gatewayBody{ReadCloser: verified, cancel: cancel}, nil
+}
+
+func (gp *GatewayPool) first(ctx context.Context, gateways []Gateway, cid string) (io.ReadCloser, int, []error) {
+	var failures []error
+
+	if !gp.Race {
+		for index, gateway := range gateways {
+			body, err := gp.open(ctx, gateway, cid)
+			if err == nil {
+				return body, index, failures
+			}
+			failures = append(failures, err)
+		}
+		return nil, -1, failures
+	}
+
+	type result struct {
+		index int
+		body  io.ReadCloser
+		err   error
+	}
+
+	cancels := make([]context.CancelFunc, len(gateways))
+	results := make(chan result, len(gateways))
+	for index, gateway := range gateways {
+		var gatewayCtx context.Context
+		gatewayCtx, cancels[index] = context.WithCancel(ctx)
+
+		go func(ctx context.Context, index int, gateway Gateway) {
+			body, err := gp.open(ctx, gateway, cid)
+			results <- result{index: index, body: body, err: err}
+		}(gatewayCtx, index, gateway)
+	}
+
+	for received := 0; received < len(gateways); received++ {
+		r := <-results
+		if r.err != nil {
+			failures = append(failures, r.err)
+			continue
+		}
+
+		for index, cancel := range cancels {
+			if index != r.index {
+				cancel()
+			}
+		}
+
+		go func(remaining int) {
+			for ; remaining > 0; remaining-- {
+				if loser := <-results; loser.err == nil {
+					_ = loser.body.Close()
+				}
+			}
+		}(len(gateways) - received - 1)
+
+		return &gatewayBody{ReadCloser: r.body, cancel: cancels[r.index]}, r.index, failures
+	}
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return nil, -1, failures
+}
+
+func (gp *GatewayPool) Open(cid string) (io.ReadCloser, error) {
+	if len(gp.Gateways) == 0 {
+		return nil, errors.New("no gateways configured")
+	}
+
+	body, _, failures := gp.first(context.Background(), gp.Gateways, cid)
+	if body == nil {
+		return nil, fmt.Errorf("all gateways failed: %v", errors.Join(failures...))
+	}
+	return body, nil
+}
+
+type trackingReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (tr *trackingReader) Read(p []byte) (int, error) {
+	n, err := tr.reader.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		tr.err = err
+	}
+	return n, err
+}
+
+func (gp *GatewayPool) OpenVerified(cid string, consume func(io.Reader) error) error {
+	return gp.openVerified(context.Background(), cid, consume)
+}
+
+func (gp *GatewayPool) openVerified(ctx context.Context, cid string, consume func(io.Reader) error) error {
+	if len(gp.Gateways) == 0 {
+		return errors.New("no gateways configured")
+	}
+
+	var failures []error
+	remaining := append([]Gateway{}, gp.Gateways...)
+	for len(remaining) > 0 {
+		body, index, opened := gp.first(ctx, remaining, cid)
+		failures = append(failures, opened...)
+		if body == nil {
+			break
+		}
+
+		tracked := &trackingReader{reader: body}
+		err := consume(tracked)
+		_ = body.Close()
+		if err == nil {
+			return nil
+		}
+		if tracked.err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		failures = append(failures, fmt.Errorf("%s: %v", remaining[index].Url, err))
+		remaining = append(remaining[:index], remaining[index+1:]...)
+	}
+
+	return fmt.Errorf("all gateways failed: %v", errors.Join(failures...))
+}
+
+func (gp *GatewayPool) GetVerified(cid string, verify func([]byte) ([]byte, error)) ([]byte, error) {
+	var data []byte
+	err := gp.OpenVerified(cid, func(reader io.Reader) error {
+		var err error
+		data, err = io.ReadAll(reader)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verify(data)
+}
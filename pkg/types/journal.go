@@ -0,0 +1,63 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+type UploadJournal struct {
+	Path     string        `json:"-"`
+	Manifest ChunkManifest `json:"manifest"`
+}
+
+func LoadUploadJournal(path string) (*UploadJournal, error) {
+	journal := &UploadJournal{Path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return journal, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, journal)
+	if err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+func (j *UploadJournal) Record(chunk ChunkEntry) error {
+	j.Manifest.Chunks = append(j.Manifest.Chunks, chunk)
+	return j.Save()
+}
+
+func (j *UploadJournal) Save() error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(j.Path), 0700)
+	if err != nil {
+		return err
+	}
+
+	temp := j.Path + ".tmp"
+	err = os.WriteFile(temp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp, j.Path)
+}
+
+func (j *UploadJournal) Remove() error {
+	err := os.Remove(j.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,208 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"ethglobal/pkg/unixfs"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type KuboClient struct {
+	ApiUrl        string
+	Authorization string
+	Client        *http.Client
+}
+
+type kuboFileStat struct {
+	Hash           string `json:"Hash"`
+	Size           int64  `json:"Size"`
+	CumulativeSize int64  `json:"CumulativeSize"`
+	Type           string `json:"Type"`
+}
+
+type kuboPins struct {
+	Keys map[string]struct {
+		Type string `json:"Type"`
+	} `json:"Keys"`
+}
+
+type kuboError struct {
+	Message string `json:"Message"`
+}
+
+func (kc *KuboClient) Name() string {
+	return "kubo"
+}
+
+func (kc *KuboClient) Accepts(id string) bool {
+	return isCid(id)
+}
+
+func (kc *KuboClient) rpc(command string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	endpoint := fmt.Sprintf("%s/api/v0/%s?%s", strings.TrimRight(kc.ApiUrl, "/"), command, query.Encode())
+
+	req, err := http.NewRequest("POST", endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if kc.Authorization != "" {
+		req.Header.Set("Authorization", kc.Authorization)
+	}
+
+	resp, err := kc.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach kubo node: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer func(Body io.ReadCloser) {
+			_ = Body.Close()
+		}(resp.Body)
+
+		message, _ := io.ReadAll(resp.Body)
+		var rpcError kuboError
+		if json.Unmarshal(message, &rpcError) == nil && rpcError.Message != "" {
+			return nil, fmt.Errorf("kubo %s error: %s", command, rpcError.Message)
+		}
+		return nil, fmt.Errorf("kubo %s error: %s", command, strings.TrimSpace(string(message)))
+	}
+
+	return resp, nil
+}
+
+func (kc *KuboClient) rpcJson(command string, query url.Values, result any) error {
+	resp, err := kc.rpc(command, query, nil, "")
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if result == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (kc *KuboClient) Put(name string, data []byte) (UploadResult, error) {
+	return kc.PutStream(name, bytes.NewReader(data))
+}
+
+func (kc *KuboClient) PutStream(name string, reader io.Reader) (UploadResult, error) {
+	payload, contentType := multipartStream(name, reader)
+
+	resp, err := kc.rpc("add", url.Values{"pin": {"true"}, "cid-version": {"0"}}, payload, contentType)
+	if err != nil {
+		_ = payload.Close()
+		return UploadResult{}, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	var added ipfsAddResponse
+	decoder := json.NewDecoder(resp.Body)
+	for decoder.More() {
+		err = decoder.Decode(&added)
+		if err != nil {
+			return UploadResult{}, fmt.Errorf("failed to read response: %v", err)
+		}
+	}
+
+	return added.result()
+}
+
+func (kc *KuboClient) PutCar(name string, root string, reader io.Reader) (UploadResult, error) {
+	payload, contentType := multipartStream(name, reader)
+
+	resp, err := kc.rpc("dag/import", url.Values{"pin-roots": {"true"}}, payload, contentType)
+	if err != nil {
+		_ = payload.Close()
+		return UploadResult{}, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	var imported dagImportResponse
+	decoder := json.NewDecoder(resp.Body)
+	for decoder.More() {
+		var event dagImportResponse
+		err = decoder.Decode(&event)
+		if err != nil {
+			return UploadResult{}, fmt.Errorf("failed to read response: %v", err)
+		}
+		if event.Root.Cid.Link != "" {
+			imported = event
+		}
+	}
+
+	return imported.result(name, root)
+}
+
+func (kc *KuboClient) Has(root string) bool {
+	var pins kuboPins
+	err := kc.rpcJson("pin/ls", url.Values{"arg": {root}, "type": {"recursive"}}, &pins)
+	if err != nil {
+		return false
+	}
+
+	_, pinned := pins.Keys[root]
+	return pinned
+}
+
+func (kc *KuboClient) Get(id string) ([]byte, error) {
+	return readAll(kc.Open(id))
+}
+
+func (kc *KuboClient) Open(id string) (io.ReadCloser, error) {
+	resp, err := kc.rpc("cat", url.Values{"arg": {id}}, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	verified, err := unixfs.NewVerifyingReader(resp.Body, id)
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return verified, nil
+}
+
+func (kc *KuboClient) Stat(id string) (StorageStat, error) {
+	var stat kuboFileStat
+	err := kc.rpcJson("files/stat", url.Values{"arg": {"/ipfs/" + id}}, &stat)
+	if err != nil {
+		return StorageStat{}, err
+	}
+
+	var pins kuboPins
+	pinned := kc.rpcJson("pin/ls", url.Values{"arg": {id}, "type": {"recursive"}}, &pins) == nil
+	if pinned {
+		_, pinned = pins.Keys[id]
+	}
+
+	return StorageStat{
+		Id:     stat.Hash,
+		Size:   stat.Size,
+		Pinned: pinned,
+	}, nil
+}
+
+func (kc *KuboClient) Pin(id string) error {
+	return kc.rpcJson("pin/add", url.Values{"arg": {id}}, nil)
+}
+
+func (kc *KuboClient) Delete(id string) error {
+	return kc.rpcJson("pin/rm", url.Values{"arg": {id}}, nil)
+}
@@ -0,0 +1,223 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type kuboStandIn struct {
+	mu       sync.Mutex
+	blocks   map[string][]byte
+	pins     map[string]bool
+	failAdds int
+	adds     int
+	tamper   bool
+}
+
+func newKuboStandIn(t *testing.T) (*kuboStandIn, *KuboClient) {
+	standIn := &kuboStandIn{blocks: map[string][]byte{}, pins: map[string]bool{}}
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	return standIn, &KuboClient{ApiUrl: server.URL + "/", Authorization: "Basic dGVzdA==", Client: server.Client()}
+}
+
+func (k *kuboStandIn) fail(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(kuboError{Message: message})
+}
+
+func (k *kuboStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if r.Method != "POST" || r.Header.Get("Authorization") != "Basic dGVzdA==" {
+		k.fail(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	arg := r.URL.Query().Get("arg")
+	switch strings.TrimPrefix(r.URL.Path, "/api/v0/") {
+	case "add":
+		k.adds++
+		if k.adds <= k.failAdds {
+			k.fail(w, http.StatusInternalServerError, "blockstore is busy")
+			return
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			k.fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		data, err := io.ReadAll(file)
+		if err != nil {
+			k.fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		root, ok := addedCids[string(data)]
+		if !ok || r.URL.Query().Get("cid-version") != "0" {
+			k.fail(w, http.StatusInternalServerError, "no ipfs add fixture for this payload")
+			return
+		}
+
+		k.blocks[root] = data
+		if r.URL.Query().Get("pin") == "true" {
+			k.pins[root] = true
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"Name": header.Filename, "Hash": root, "Size": len(data)})
+	case "cat":
+		data, ok := k.blocks[arg]
+		if !ok {
+			k.fail(w, http.StatusInternalServerError, "block was not found locally (offline)")
+			return
+		}
+		if k.tamper {
+			data = append([]byte("x"), data[1:]...)
+		}
+		_, _ = w.Write(data)
+	case "files/stat":
+		data, ok := k.blocks[strings.TrimPrefix(arg, "/ipfs/")]
+		if !ok {
+			k.fail(w, http.StatusInternalServerError, "not found")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(kuboFileStat{Hash: strings.TrimPrefix(arg, "/ipfs/"), Size: int64(len(data)), Type: "file"})
+	case "pin/ls":
+		if !k.pins[arg] {
+			k.fail(w, http.StatusInternalServerError, "path '"+arg+"' is not pinned")
+			return
+		}
+		_, _ = w.Write([]byte(`{"Keys":{"` + arg + `":{"Type":"recursive"}}}`))
+	case "pin/add":
+		k.pins[arg] = true
+		_, _ = w.Write([]byte(`{"Pins":["` + arg + `"]}`))
+	case "pin/rm":
+		if !k.pins[arg] {
+			k.fail(w, http.StatusInternalServerError, "not pinned or pinned indirectly")
+			return
+		}
+		delete(k.pins, arg)
+		_, _ = w.Write([]byte(`{"Pins":["` + arg + `"]}`))
+	default:
+		k.fail(w, http.StatusNotFound, "unknown command")
+	}
+}
+
+func TestKuboUploadRoundTrip(t *testing.T) {
+	standIn, kubo := newKuboStandIn(t)
+	data := []byte(archivePayload)
+
+	result, err := kubo.PutStream("repo.git", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Cid != "QmW8zFxKfQd8UtVpLgKbPu6rJu7wfRQuEZ8d9Rn5b7tJc2" {
+		t.Fatalf("unexpected cid %s", result.Cid)
+	}
+	standIn.mu.Lock()
+	pinned := standIn.pins[result.Cid]
+	standIn.mu.Unlock()
+	if result.Name != "repo.git" || result.Size != int64(len(data)) || !pinned {
+		t.Fatalf("unexpected upload result %+v", result)
+	}
+
+	downloaded, err := kubo.Get(result.Cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatal("downloaded content differs from the upload")
+	}
+
+	stat, err := kubo.Stat(result.Cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size != int64(len(data)) || !stat.Pinned {
+		t.Fatalf("unexpected stat %+v", stat)
+	}
+
+	err = kubo.Delete(result.Cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kubo.Has(result.Cid) {
+		t.Fatal("content still pinned after delete")
+	}
+
+	err = kubo.Pin(result.Cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !kubo.Has(result.Cid) {
+		t.Fatal("content not pinned after pin")
+	}
+}
+
+func TestKuboUploadRetry(t *testing.T) {
+	standIn, kubo := newKuboStandIn(t)
+	standIn.failAdds = 2
+	data := []byte("chunk contents")
+
+	var result UploadResult
+	var errs []error
+	for attempt := 0; attempt < 3; attempt++ {
+		var err error
+		result, err = kubo.PutStream("repo.git.part0", io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data))))
+		if err == nil {
+			break
+		}
+		errs = append(errs, err)
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 failed attempts, got %v", errs)
+	}
+	for _, err := range errs {
+		if err.Error() != "kubo add error: blockstore is busy" {
+			t.Fatalf("unexpected error %q", err)
+		}
+	}
+
+	downloaded, err := kubo.Get(result.Cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatal("downloaded content differs from the upload")
+	}
+}
+
+func TestKuboRejectsWrongContent(t *testing.T) {
+	standIn, kubo := newKuboStandIn(t)
+
+	result, err := kubo.Put("repo.git", []byte("original content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	standIn.mu.Lock()
+	standIn.tamper = true
+	standIn.mu.Unlock()
+	_, err = kubo.Get(result.Cid)
+	if err == nil {
+		t.Fatal("tampered content was accepted")
+	}
+}
+
+func TestKuboMissingContent(t *testing.T) {
+	_, kubo := newKuboStandIn(t)
+
+	_, err := kubo.Open("QmbWqxBEKC3P8tqsKc98xmWNzrzDtRLMiMPL8wBuTGsMnR")
+	if err == nil || !strings.Contains(err.Error(), "block was not found locally") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+type LedgerEntry struct {
+	Version     uint32 `json:"version"`
+	CommitHash  string `json:"commit_hash"`
+	Transaction string `json:"transaction"`
+	RecordedAt  int64  `json:"recorded_at"`
+}
+
+type Ledger struct {
+	Path    string        `json:"-"`
+	Entries []LedgerEntry `json:"entries"`
+}
+
+func LoadLedger(path string) (*Ledger, error) {
+	ledger := &Ledger{Path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, ledger)
+	if err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+func (l *Ledger) Record(entry LedgerEntry) error {
+	for index, existing := range l.Entries {
+		if existing.Version == entry.Version {
+			l.Entries[index] = entry
+			return l.Save()
+		}
+	}
+
+	l.Entries = append(l.Entries, entry)
+	return l.Save()
+}
+
+func (l *Ledger) Transaction(version VersionMetaData) string {
+	for _, entry := range l.Entries {
+		if entry.Version == version.Version && entry.CommitHash == version.CommitHash {
+			return entry.Transaction
+		}
+	}
+	return ""
+}
+
+func (l *Ledger) Save() error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(l.Path), 0700)
+	if err != nil {
+		return err
+	}
+
+	temp := l.Path + ".tmp"
+	err = os.WriteFile(temp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp, l.Path)
+}
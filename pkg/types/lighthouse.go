@@ -2,109 +2,316 @@ package types
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"ethglobal/pkg/utils"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 )
 
 type LighthouseClient struct {
+	ApiUrl      string
+	UploadUrl   string
 	ApiKey      string
 	ApiKeyBytes []byte
 	Client      *http.Client
+	Gateways    *GatewayPool
 }
 
-func (lh *LighthouseClient) UploadFile(plainBuf []byte, encryptionKey []byte, commitHash string) (string, error) {
-	cipherText, err := utils.Encrypt(encryptionKey, plainBuf)
-	if err != nil {
-		return "", err
-	}
+type lighthouseFileInfo struct {
+	Cid             string      `json:"cid"`
+	FileSizeInBytes json.Number `json:"fileSizeInBytes"`
+}
 
-	var cipherBuffer bytes.Buffer
-	writer := multipart.NewWriter(&cipherBuffer)
+type lighthouseUpload struct {
+	Id  string `json:"id"`
+	Cid string `json:"cid"`
+}
 
-	part, err := writer.CreateFormFile("file", fmt.Sprintf("%v.git", commitHash))
-	if err != nil {
-		return "", err
+type lighthouseUploads struct {
+	FileList   []lighthouseUpload `json:"fileList"`
+	TotalFiles int                `json:"totalFiles"`
+}
+
+func lighthouseHash(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if !strings.HasPrefix(id, "{") {
+		return id, nil
 	}
-	_, err = part.Write(cipherText)
+
+	var result map[string]string
+	err := json.Unmarshal([]byte(id), &result)
 	if err != nil {
 		return "", err
 	}
 
-	_ = writer.Close()
-	req, err := http.NewRequest("POST", "https://upload.lighthouse.storage/api/v0/add", &cipherBuffer)
+	return result["Hash"], nil
+}
 
+func (lh *LighthouseClient) Name() string {
+	return "lighthouse"
+}
+
+func (lh *LighthouseClient) Accepts(id string) bool {
+	return isCid(id)
+}
+
+func (lh *LighthouseClient) Put(name string, data []byte) (UploadResult, error) {
+	return lh.PutStream(name, bytes.NewReader(data))
+}
+
+func (lh *LighthouseClient) post(path string, name string, reader io.Reader) ([]byte, error) {
+	payload, contentType := multipartStream(name, reader)
+
+	req, err := http.NewRequest("POST", strings.TrimRight(lh.UploadUrl, "/")+path, payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+		_ = payload.Close()
+		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Authorization", "Bearer "+lh.ApiKey)
 
 	resp, err := lh.Client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload file: %v", err)
+		return nil, fmt.Errorf("failed to upload file: %v", err)
 	}
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
 	}(resp.Body)
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("lighthouse API error: %s", string(body))
+		return nil, fmt.Errorf("lighthouse API error: %s", string(body))
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	return body, nil
+}
+
+func (lh *LighthouseClient) PutStream(name string, reader io.Reader) (UploadResult, error) {
+	body, err := lh.post("/api/v0/add", name, reader)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %v", err)
+		return UploadResult{}, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("lighthouse API error: %s", string(body))
+
+	var added ipfsAddResponse
+	err = json.Unmarshal(body, &added)
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("invalid lighthouse response %s: %v", strings.TrimSpace(string(body)), err)
 	}
-	return strings.TrimSpace(string(body)), nil
+	return added.result()
 }
 
-func (lh *LighthouseClient) DownloadFile(bytes string, encryptionKey []byte) ([]byte, error) {
-	var result map[string]string
-	err := json.Unmarshal([]byte(bytes), &result)
+func (lh *LighthouseClient) PutCar(name string, root string, reader io.Reader) (UploadResult, error) {
+	body, err := lh.post("/api/v0/dag/import", name, reader)
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	var imported dagImportResponse
+	err = json.Unmarshal(bytes.TrimSpace(body), &imported)
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("invalid lighthouse response %s: %v", strings.TrimSpace(string(body)), err)
+	}
+	return imported.result(name, root)
+}
+
+func (lh *LighthouseClient) Has(root string) bool {
+	_, err := lh.Stat(root)
+	return err == nil
+}
+
+func (lh *LighthouseClient) Get(id string) ([]byte, error) {
+	return readAll(lh.Open(id))
+}
+
+func (lh *LighthouseClient) Open(id string) (io.ReadCloser, error) {
+	hash, err := lighthouseHash(id)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://gateway.lighthouse.storage/ipfs/%s", result["Hash"]), nil)
+	return lh.Gateways.Open(hash)
+}
+
+func (lh *LighthouseClient) OpenVerified(id string, consume func(io.Reader) error) error {
+	return lh.openVerified(context.Background(), id, consume)
+}
+
+func (lh *LighthouseClient) openVerified(ctx context.Context, id string, consume func(io.Reader) error) error {
+	hash, err := lighthouseHash(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return err
+	}
+
+	return lh.Gateways.openVerified(ctx, hash, consume)
+}
+
+func (lh *LighthouseClient) GetVerified(id string, verify func([]byte) ([]byte, error)) ([]byte, error) {
+	hash, err := lighthouseHash(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return lh.Gateways.GetVerified(hash, verify)
+}
+
+func (lh *LighthouseClient) api(method string, path string, query url.Values, result any) error {
+	req, err := http.NewRequest(method, fmt.Sprintf("%s%s?%s", strings.TrimRight(lh.ApiUrl, "/"), path, query.Encode()), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
 	}
 
+	req.Header.Set("Authorization", "Bearer "+lh.ApiKey)
+
 	resp, err := lh.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download file: %v", err)
+		return fmt.Errorf("failed to reach lighthouse API: %v", err)
 	}
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
 	}(resp.Body)
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("lighthouse API error: %s", string(body))
+		return fmt.Errorf("lighthouse API error: %s", string(body))
 	}
 
-	cipherBuf, err := io.ReadAll(resp.Body)
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(body, result)
+}
+
+func (lh *LighthouseClient) Stat(id string) (StorageStat, error) {
+	hash, err := lighthouseHash(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %v", err)
+		return StorageStat{}, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("lighthouse API error: %s", string(cipherBuf))
+
+	var info lighthouseFileInfo
+	err = lh.api("GET", "/api/lighthouse/file_info", url.Values{"cid": {hash}}, &info)
+	if err != nil {
+		return StorageStat{}, err
 	}
 
-	plainBuf, err := utils.Decrypt(encryptionKey, cipherBuf)
+	size, err := strconv.ParseInt(info.FileSizeInBytes.String(), 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt file: %v", err)
+		return StorageStat{}, fmt.Errorf("invalid file size %q: %v", info.FileSizeInBytes, err)
 	}
 
-	return plainBuf, nil
+	return StorageStat{
+		Id:     hash,
+		Size:   size,
+		Pinned: true,
+	}, nil
+}
+
+func (lh *LighthouseClient) uploads() ([]lighthouseUpload, error) {
+	var files []lighthouseUpload
+
+	query := url.Values{}
+	for {
+		var page lighthouseUploads
+		err := lh.api("GET", "/api/user/files_uploaded", query, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(page.FileList) == 0 {
+			return files, nil
+		}
+
+		lastKey := page.FileList[len(page.FileList)-1].Id
+		if query.Has("lastKey") && lastKey == query.Get("lastKey") {
+			return files, nil
+		}
+
+		files = append(files, page.FileList...)
+		if lastKey == "" || page.TotalFiles > 0 && len(files) >= page.TotalFiles {
+			return files, nil
+		}
+		query.Set("lastKey", lastKey)
+	}
+}
+
+func (lh *LighthouseClient) Delete(id string) error {
+	hash, err := lighthouseHash(id)
+	if err != nil {
+		return err
+	}
+
+	files, err := lh.uploads()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.Cid != hash {
+			continue
+		}
+
+		err = lh.api("DELETE", "/api/user/delete_file", url.Values{"id": {file.Id}}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lh *LighthouseClient) DealStatus(cid string) ([]Deal, error) {
+	hash, err := lighthouseHash(cid)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw json.RawMessage
+	err = lh.api("GET", "/api/lighthouse/deal_status", url.Values{"cid": {hash}}, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var deals []Deal
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		var wrapped struct {
+			Data []Deal `json:"data"`
+		}
+		err = json.Unmarshal(raw, &wrapped)
+		deals = wrapped.Data
+	} else {
+		err = json.Unmarshal(raw, &deals)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return deals, nil
+}
+
+func (lh *LighthouseClient) Proof(cid string, network string) (LighthouseProof, error) {
+	hash, err := lighthouseHash(cid)
+	if err != nil {
+		return LighthouseProof{}, err
+	}
+
+	var proof LighthouseProof
+	err = lh.api("GET", "/api/lighthouse/get_proof", url.Values{"cid": {hash}, "network": {network}}, &proof)
+	if err != nil {
+		return LighthouseProof{}, err
+	}
+
+	if proof.PieceCid == "" {
+		return LighthouseProof{}, fmt.Errorf("no inclusion proof for %s yet", hash)
+	}
+	return proof, nil
 }
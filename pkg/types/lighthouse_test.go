@@ -0,0 +1,210 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gatewayStandIn(t *testing.T, serve func(w http.ResponseWriter, cid string)) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/ipfs/") {
+			http.NotFound(w, r)
+			return
+		}
+		serve(w, strings.TrimPrefix(r.URL.Path, "/ipfs/"))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func lighthouseStandIn(t *testing.T, routes map[string]string) *LighthouseClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"Authentication failed"}`))
+			return
+		}
+
+		body, ok := routes[r.URL.Path+"?"+r.URL.RawQuery]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return &LighthouseClient{ApiUrl: server.URL, ApiKey: "key", Client: server.Client()}
+}
+
+func TestGatewayMetadataFailover(t *testing.T) {
+	metaData := []byte(`[{"version":0,"commit_hash":"1a2b3c"}]`)
+	const hash = "QmXToxHaVrf4LGCALY5tgvh8Kt4Pj75iYLGjCZwwXNYvM2"
+
+	down := gatewayStandIn(t, func(w http.ResponseWriter, cid string) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	errorPage := gatewayStandIn(t, func(w http.ResponseWriter, cid string) {
+		_, _ = w.Write([]byte("<html>rate limited</html>"))
+	})
+	truncated := gatewayStandIn(t, func(w http.ResponseWriter, cid string) {
+		_, _ = w.Write(metaData[:len(metaData)/2])
+	})
+	healthy := gatewayStandIn(t, func(w http.ResponseWriter, cid string) {
+		if cid != hash {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(metaData)
+	})
+
+	for _, race := range []bool{false, true} {
+		lighthouse := &LighthouseClient{Gateways: &GatewayPool{
+			Gateways: []Gateway{{Url: down}, {Url: errorPage}, {Url: truncated}, {Url: healthy, Timeout: 5 * time.Second}},
+			Race:     race,
+			Client:   http.DefaultClient,
+		}}
+
+		var versions []VersionMetaData
+		data, err := lighthouse.GetVerified(hash, func(data []byte) ([]byte, error) {
+			return data, json.Unmarshal(data, &versions)
+		})
+		if err != nil {
+			t.Fatalf("race %v: %v", race, err)
+		}
+		if !bytes.Equal(data, metaData) || len(versions) != 1 || versions[0].CommitHash != "1a2b3c" {
+			t.Fatalf("race %v: unexpected metadata %s", race, data)
+		}
+	}
+}
+
+func TestGatewayAllFailing(t *testing.T) {
+	down := gatewayStandIn(t, func(w http.ResponseWriter, cid string) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+	const hash = "QmYfywbHo9kxXz2y3urV8neac2wKjZz6W2Hp9Nqb3NGNvj"
+
+	lighthouse := &LighthouseClient{Gateways: &GatewayPool{Gateways: []Gateway{{Url: down}, {Url: down}}, Client: http.DefaultClient}}
+	_, err := lighthouse.Get(hash)
+	if err == nil || !strings.Contains(err.Error(), "504") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestGatewayStalledBody(t *testing.T) {
+	data := bytes.Repeat([]byte("archive"), 1000)
+	const hash = "QmXe8fGepZ9ZNEM1uzVcJK454fUyttA8iik8CpYsLdeffZ"
+
+	release := make(chan struct{})
+	stalled := gatewayStandIn(t, func(w http.ResponseWriter, cid string) {
+		_, _ = w.Write(data[:100])
+		w.(http.Flusher).Flush()
+		<-release
+	})
+	t.Cleanup(func() { close(release) })
+	healthy := gatewayStandIn(t, func(w http.ResponseWriter, cid string) {
+		_, _ = w.Write(data)
+	})
+
+	lighthouse := &LighthouseClient{Gateways: &GatewayPool{
+		Gateways: []Gateway{{Url: stalled, Timeout: 200 * time.Millisecond}, {Url: healthy}},
+		Client:   http.DefaultClient,
+	}}
+
+	downloaded, err := lighthouse.GetVerified(hash, func(data []byte) ([]byte, error) { return data, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatal("downloaded content differs")
+	}
+}
+
+func TestGatewayVerifyFailure(t *testing.T) {
+	data := []byte("not json")
+	const hash = "QmRyxw2K8u9wNHXCdjXcoRcK2gDB7ypa35P7GmQxKUMUoL"
+	healthy := gatewayStandIn(t, func(w http.ResponseWriter, cid string) {
+		_, _ = w.Write(data)
+	})
+
+	lighthouse := &LighthouseClient{Gateways: &GatewayPool{Gateways: []Gateway{{Url: healthy}}, Client: http.DefaultClient}}
+	invalid := errors.New("invalid metadata")
+	_, err := lighthouse.GetVerified(hash, func(data []byte) ([]byte, error) { return nil, invalid })
+	if !errors.Is(err, invalid) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestDealStatus(t *testing.T) {
+	const hash = "QmbWqxBEKC3P8tqsKc98xmWNzrzDtRLMiMPL8wBuTGsMnR"
+	deals := `[
+		{"chainDealID":"1234","storageProvider":"f01","dealStatus":"Active","startEpoch":100,"endEpoch":"1540000","pieceCID":"baga6ea4seaq","pieceSize":"34359738368","payloadCid":"` + hash + `","aggregateIn":"agg"},
+		{"dealId":5678,"miner":"f02","dealStatus":"Active","startEpoch":100,"endEpoch":1540000},
+		{"dealId":9012,"miner":"f02","dealStatus":"Active"},
+		{"dealUUID":"pending","storageProvider":"f03","dealStatus":"Proposed","chainDealID":null}
+	]`
+
+	for name, body := range map[string]string{"plain": deals, "wrapped": `{"data":` + deals + `}`} {
+		lighthouse := lighthouseStandIn(t, map[string]string{"/api/lighthouse/deal_status?cid=" + hash: body})
+
+		result, err := lighthouse.DealStatus(hash)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(result) != 4 {
+			t.Fatalf("%s: expected 4 deals, got %d", name, len(result))
+		}
+
+		if result[0].Id() != 1234 || result[0].Provider() != "f01" || result[0].EndEpoch != 1540000 || result[0].PieceSize != 34359738368 {
+			t.Fatalf("%s: unexpected deal %+v", name, result[0])
+		}
+		if result[1].Id() != 5678 || result[1].Provider() != "f02" {
+			t.Fatalf("%s: unexpected deal %+v", name, result[1])
+		}
+		if result[3].Id() != 0 || result[3].Provider() != "f03" {
+			t.Fatalf("%s: unexpected deal %+v", name, result[3])
+		}
+
+		replication := CidDeals{Cid: hash, Deals: result}.Replication()
+		if replication != 2 {
+			t.Fatalf("%s: expected replication 2, got %d", name, replication)
+		}
+	}
+}
+
+func TestDealStatusErrors(t *testing.T) {
+	lighthouse := lighthouseStandIn(t, map[string]string{})
+
+	_, err := lighthouse.DealStatus("QmbWqxBEKC3P8tqsKc98xmWNzrzDtRLMiMPL8wBuTGsMnR")
+	if err == nil || !strings.Contains(err.Error(), "lighthouse API error") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	lighthouse.ApiKey = "wrong"
+	_, err = lighthouse.DealStatus("QmbWqxBEKC3P8tqsKc98xmWNzrzDtRLMiMPL8wBuTGsMnR")
+	if err == nil || !strings.Contains(err.Error(), "Authentication failed") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestUploadsStopsWhenLastKeyStalls(t *testing.T) {
+	const page = `{"fileList":[{"id":"a","cid":"QmA"},{"id":"b","cid":"QmB"}],"totalFiles":5}`
+	lighthouse := lighthouseStandIn(t, map[string]string{
+		"/api/user/files_uploaded?":          page,
+		"/api/user/files_uploaded?lastKey=b": page,
+	})
+
+	files, err := lighthouse.uploads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 uploads, got %d", len(files))
+	}
+}
@@ -0,0 +1,129 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type LocalStorage struct {
+	Directory string
+}
+
+func (ls *LocalStorage) Name() string {
+	return "local"
+}
+
+func (ls *LocalStorage) Accepts(id string) bool {
+	_, err := ls.path(id)
+	return err == nil
+}
+
+func (ls *LocalStorage) path(id string) (string, error) {
+	decoded, err := hex.DecodeString(id)
+	if err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid local content id: %q", id)
+	}
+
+	return filepath.Join(ls.Directory, id[:2], id), nil
+}
+
+func (ls *LocalStorage) Put(name string, data []byte) (UploadResult, error) {
+	return ls.PutStream(name, bytes.NewReader(data))
+}
+
+func (ls *LocalStorage) PutStream(name string, reader io.Reader) (UploadResult, error) {
+	err := os.MkdirAll(ls.Directory, 0755)
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	temp, err := os.CreateTemp(ls.Directory, ".put-*")
+	if err != nil {
+		return UploadResult{}, err
+	}
+	defer func() {
+		_ = os.Remove(temp.Name())
+	}()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(temp, hash), reader)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	id := hex.EncodeToString(hash.Sum(nil))
+	path, err := ls.path(id)
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	err = os.Rename(temp.Name(), path)
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	return UploadResult{Name: name, Cid: id, Size: size}, nil
+}
+
+func (ls *LocalStorage) Get(id string) ([]byte, error) {
+	return readAll(ls.Open(id))
+}
+
+func (ls *LocalStorage) Open(id string) (io.ReadCloser, error) {
+	path, err := ls.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local content %s: %v", id, err)
+	}
+
+	return newDigestReader(file, id, fmt.Errorf("local content %s is corrupt", id)), nil
+}
+
+func (ls *LocalStorage) Stat(id string) (StorageStat, error) {
+	path, err := ls.path(id)
+	if err != nil {
+		return StorageStat{}, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return StorageStat{}, err
+	}
+
+	return StorageStat{
+		Id:     id,
+		Size:   info.Size(),
+		Pinned: true,
+	}, nil
+}
+
+func (ls *LocalStorage) Delete(id string) error {
+	path, err := ls.path(id)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package types
+
+type ChunkEntry struct {
+	Index  int    `json:"index"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+	Id     string `json:"id"`
+
+	Locations []StorageLocation `json:"locations,omitempty"`
+}
+
+type ChunkManifest struct {
+	Name      string       `json:"name"`
+	ChunkSize int64        `json:"chunk_size"`
+	Size      int64        `json:"size"`
+	Sha256    string       `json:"sha256"`
+	Chunks    []ChunkEntry `json:"chunks"`
+}
+
+func (m *ChunkManifest) ChunkCount() int {
+	if m.Size == 0 {
+		return 1
+	}
+	return int((m.Size + m.ChunkSize - 1) / m.ChunkSize)
+}
+
+func (m *ChunkManifest) Chunk(index int) (ChunkEntry, bool) {
+	for _, chunk := range m.Chunks {
+		if chunk.Index == index {
+			return chunk, true
+		}
+	}
+	return ChunkEntry{}, false
+}
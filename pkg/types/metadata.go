@@ -1,6 +1,89 @@
 package types
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 type VersionMetaData struct {
-	Version    uint32 `json:"version"`
-	CommitHash string `json:"commit_hash"`
+	Version          uint32            `json:"version"`
+	CommitHash       string            `json:"commit_hash"`
+	Parent           uint32            `json:"parent,omitempty"`
+	Format           string            `json:"format,omitempty"`
+	ArchiveSha256    string            `json:"archive_sha256,omitempty"`
+	Fingerprint      string            `json:"fingerprint,omitempty"`
+	Size             int64             `json:"size,omitempty"`
+	Timestamp        int64             `json:"timestamp,omitempty"`
+	Transaction      string            `json:"transaction,omitempty"`
+	Refs             map[string]string `json:"refs,omitempty"`
+	Head             string            `json:"head,omitempty"`
+	Peeled           map[string]string `json:"peeled,omitempty"`
+	Locations        []StorageLocation `json:"locations,omitempty"`
+	Chunked          bool              `json:"chunked,omitempty"`
+	Release          bool              `json:"release,omitempty"`
+	Proofs           []InclusionRecord `json:"proofs,omitempty"`
+	PreviousMetaData []StorageLocation `json:"previous_metadata,omitempty"`
+}
+
+type LogEntry struct {
+	Version     uint32            `json:"version"`
+	CommitHash  string            `json:"commit_hash"`
+	Refs        map[string]string `json:"refs,omitempty"`
+	Release     bool              `json:"release,omitempty"`
+	Parent      uint32            `json:"parent,omitempty"`
+	Size        int64             `json:"size,omitempty"`
+	Cid         string            `json:"cid,omitempty"`
+	Transaction string            `json:"transaction,omitempty"`
+	Block       uint64            `json:"block,omitempty"`
+	Timestamp   int64             `json:"timestamp,omitempty"`
+}
+
+type RefVersion struct {
+	Version uint32 `json:"version"`
+	Ref     string `json:"ref"`
+	Oid     string `json:"oid"`
+	Commit  string `json:"commit"`
+}
+
+type VersionSelector struct {
+	Version uint32
+	Commit  string
+}
+
+func (vs VersionSelector) Select(versions []VersionMetaData) (VersionMetaData, error) {
+	if len(versions) == 0 {
+		return VersionMetaData{}, errors.New("repository has no archived versions")
+	}
+
+	if vs.Version != 0 {
+		for _, version := range versions {
+			if version.Version == vs.Version {
+				return version, nil
+			}
+		}
+		return VersionMetaData{}, fmt.Errorf("version %d does not exist", vs.Version)
+	}
+
+	if vs.Commit == "" {
+		return versions[len(versions)-1], nil
+	}
+
+	var match *VersionMetaData
+	for index := len(versions) - 1; index >= 0; index-- {
+		if !strings.HasPrefix(versions[index].CommitHash, strings.ToLower(vs.Commit)) {
+			continue
+		}
+		if match != nil && match.CommitHash != versions[index].CommitHash {
+			return VersionMetaData{}, fmt.Errorf("commit %s is ambiguous", vs.Commit)
+		}
+		if match == nil {
+			match = &versions[index]
+		}
+	}
+
+	if match == nil {
+		return VersionMetaData{}, fmt.Errorf("no archived version has commit %s", vs.Commit)
+	}
+	return *match, nil
 }
@@ -0,0 +1,43 @@
+package types
+
+type Pinner interface {
+	Pin(id string) error
+}
+
+type PinTarget struct {
+	Label     string
+	Version   uint32
+	Keep      bool
+	Locations []StorageLocation
+}
+
+type PinStatus struct {
+	Label   string `json:"label"`
+	Version uint32 `json:"version"`
+	Keep    bool   `json:"keep"`
+	Backend string `json:"backend"`
+	Id      string `json:"id"`
+	Pinned  bool   `json:"pinned"`
+	Size    int64  `json:"size"`
+	Error   string `json:"error,omitempty"`
+}
+
+func Backend(storage StorageBackend, name string) StorageBackend {
+	if replicated, ok := storage.(*ReplicatedStorage); ok {
+		return replicated.Backend(name)
+	}
+	if storage.Name() == name {
+		return storage
+	}
+	return nil
+}
+
+func (pt PinTarget) Status(location StorageLocation) PinStatus {
+	return PinStatus{
+		Label:   pt.Label,
+		Version: pt.Version,
+		Keep:    pt.Keep,
+		Backend: location.Backend,
+		Id:      location.Id,
+	}
+}
@@ -0,0 +1,176 @@
+package types
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"ethglobal/pkg/podsi"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+)
+
+type HexUint uint64
+
+type HexBytes []byte
+
+type lighthouseProofData struct {
+	Index HexUint    `json:"index"`
+	Path  []HexBytes `json:"path"`
+}
+
+type LighthouseProof struct {
+	PieceCid  string `json:"pieceCID"`
+	FileProof struct {
+		InclusionProof struct {
+			ProofIndex   lighthouseProofData `json:"proofIndex"`
+			ProofSubtree lighthouseProofData `json:"proofSubtree"`
+		} `json:"inclusionProof"`
+		VerifierData struct {
+			CommPc string  `json:"commPc"`
+			SizePc HexUint `json:"sizePc"`
+		} `json:"verifierData"`
+	} `json:"fileProof"`
+	DealInfo []Deal `json:"dealInfo"`
+}
+
+type InclusionDeal struct {
+	DealId   int64  `json:"deal_id"`
+	Provider string `json:"provider"`
+}
+
+type InclusionRecord struct {
+	Cid           string          `json:"cid"`
+	PieceCid      string          `json:"piece_cid"`
+	PieceSize     uint64          `json:"piece_size"`
+	AggregateCid  string          `json:"aggregate_cid"`
+	AggregateSize uint64          `json:"aggregate_size"`
+	Deals         []InclusionDeal `json:"deals,omitempty"`
+	VerifiedAt    int64           `json:"verified_at"`
+}
+
+type CidProof struct {
+	Label  string           `json:"label"`
+	Cid    string           `json:"cid"`
+	Record *InclusionRecord `json:"record,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+func (h *HexUint) UnmarshalJSON(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(`"`)) {
+		value, err := strconv.ParseUint(string(data), 10, 64)
+		*h = HexUint(value)
+		return err
+	}
+
+	var text string
+	err := json.Unmarshal(data, &text)
+	if err != nil {
+		return err
+	}
+
+	value, err := strconv.ParseUint(strings.TrimPrefix(text, "0x"), 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid hex number %q", text)
+	}
+
+	*h = HexUint(value)
+	return nil
+}
+
+func (h *HexBytes) UnmarshalJSON(data []byte) error {
+	var text string
+	err := json.Unmarshal(data, &text)
+	if err != nil {
+		return err
+	}
+
+	decoded, err := hex.DecodeString(strings.TrimPrefix(text, "0x"))
+	if err != nil {
+		decoded, err = base64.StdEncoding.DecodeString(text)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid proof node %q", text)
+	}
+
+	*h = decoded
+	return nil
+}
+
+func proofData(data lighthouseProofData) (podsi.ProofData, error) {
+	proof := podsi.ProofData{Index: uint64(data.Index)}
+	for _, node := range data.Path {
+		if len(node) != podsi.NodeSize {
+			return podsi.ProofData{}, fmt.Errorf("invalid proof node length %d", len(node))
+		}
+		proof.Path = append(proof.Path, podsi.Node(node))
+	}
+	return proof, nil
+}
+
+func parsePieceCid(text string) (cid.Cid, error) {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(text, "0x"))
+	if err == nil {
+		return cid.Cast(decoded)
+	}
+	return cid.Decode(text)
+}
+
+func (lp LighthouseProof) Verify(payloadCid string, pieces []podsi.Piece) (InclusionRecord, error) {
+	subtree, err := proofData(lp.FileProof.InclusionProof.ProofSubtree)
+	if err != nil {
+		return InclusionRecord{}, err
+	}
+
+	index, err := proofData(lp.FileProof.InclusionProof.ProofIndex)
+	if err != nil {
+		return InclusionRecord{}, err
+	}
+
+	commPc, err := parsePieceCid(lp.FileProof.VerifierData.CommPc)
+	if err != nil {
+		return InclusionRecord{}, fmt.Errorf("invalid piece cid %q: %v", lp.FileProof.VerifierData.CommPc, err)
+	}
+
+	size := uint64(lp.FileProof.VerifierData.SizePc)
+	matched := false
+	for _, piece := range pieces {
+		matched = matched || piece.Cid.Equals(commPc) && piece.Size == size
+	}
+	if !matched {
+		computed := make([]string, 0, len(pieces))
+		for _, piece := range pieces {
+			computed = append(computed, fmt.Sprintf("%s (%d bytes)", piece.Cid, piece.Size))
+		}
+		return InclusionRecord{}, fmt.Errorf("proof is for piece %s (%d bytes), but the archive's piece is %s", commPc, size, strings.Join(computed, " or "))
+	}
+
+	aggregate, err := parsePieceCid(lp.PieceCid)
+	if err != nil {
+		return InclusionRecord{}, fmt.Errorf("invalid aggregate piece cid %q: %v", lp.PieceCid, err)
+	}
+
+	verifier := podsi.VerifierData{CommPc: commPc, SizePc: size}
+	aux, err := podsi.Verify(podsi.InclusionProof{ProofSubtree: subtree, ProofIndex: index}, verifier, aggregate)
+	if err != nil {
+		return InclusionRecord{}, fmt.Errorf("inclusion proof rejected: %v", err)
+	}
+
+	record := InclusionRecord{
+		Cid:           payloadCid,
+		PieceCid:      commPc.String(),
+		PieceSize:     verifier.SizePc,
+		AggregateCid:  aux.CommPa.String(),
+		AggregateSize: aux.SizePa,
+	}
+	for _, deal := range lp.DealInfo {
+		if deal.Id() != 0 {
+			record.Deals = append(record.Deals, InclusionDeal{DealId: deal.Id(), Provider: deal.Provider()})
+		}
+	}
+
+	return record, nil
+}
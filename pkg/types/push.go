@@ -0,0 +1,10 @@
+package types
+
+type PushOptions struct {
+	Release bool
+	Force   bool
+	Full    bool
+	Format  string
+
+	ReleaseTags []string
+}
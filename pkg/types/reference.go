@@ -0,0 +1,28 @@
+package types
+
+import (
+	"bytes"
+
+	"github.com/ipfs/go-cid"
+)
+
+func EncodeReference(id string) []byte {
+	c, err := cid.Decode(id)
+	if err != nil {
+		return []byte(id)
+	}
+	return c.Bytes()
+}
+
+func DecodeReference(reference []byte) (string, error) {
+	trimmed := bytes.TrimSpace(reference)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return lighthouseHash(string(trimmed))
+	}
+
+	c, err := cid.Cast(reference)
+	if err == nil {
+		return c.String(), nil
+	}
+	return string(reference), nil
+}
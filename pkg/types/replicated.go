@@ -0,0 +1,483 @@
+package types
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"ethglobal/pkg/utils"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type StorageLocation struct {
+	Backend string `json:"backend"`
+	Id      string `json:"id"`
+}
+
+type ReplicatedStorage struct {
+	Backends    []StorageBackend
+	MinReplicas int
+	Race        bool
+}
+
+type VerifiedGetter interface {
+	GetVerified(id string, verify func([]byte) ([]byte, error)) ([]byte, error)
+	OpenVerified(id string, consume func(io.Reader) error) error
+}
+
+type contextOpener interface {
+	openVerified(ctx context.Context, id string, consume func(io.Reader) error) error
+}
+
+func Locations(storage StorageBackend, id string) []StorageLocation {
+	if replicated, ok := storage.(*ReplicatedStorage); ok {
+		return replicated.locations(id)
+	}
+	return []StorageLocation{{Backend: storage.Name(), Id: id}}
+}
+
+func ResultLocations(storage StorageBackend, result UploadResult) []StorageLocation {
+	if len(result.Locations) > 0 {
+		return result.Locations
+	}
+	return Locations(storage, result.Cid)
+}
+
+func located(storage StorageBackend, locations []StorageLocation) (string, error) {
+	if len(locations) == 0 {
+		return "", errors.New("no storage locations recorded")
+	}
+
+	backends := make([]string, 0, len(locations))
+	for _, location := range locations {
+		if location.Backend == storage.Name() {
+			return location.Id, nil
+		}
+		backends = append(backends, location.Backend)
+	}
+	return "", fmt.Errorf("stored on %s, but the configured storage backend is %s", strings.Join(backends, ", "), storage.Name())
+}
+
+func GetLocations(storage StorageBackend, locations []StorageLocation, verify func([]byte) ([]byte, error)) ([]byte, error) {
+	if replicated, ok := storage.(*ReplicatedStorage); ok {
+		return replicated.getVerified(locations, verify)
+	}
+
+	id, err := located(storage, locations)
+	if err != nil {
+		return nil, err
+	}
+	return GetVerified(storage, id, verify)
+}
+
+func OpenLocations(storage StorageBackend, locations []StorageLocation, consume func(io.Reader) error) error {
+	if replicated, ok := storage.(*ReplicatedStorage); ok {
+		return replicated.openVerified(locations, consume)
+	}
+
+	id, err := located(storage, locations)
+	if err != nil {
+		return err
+	}
+	return OpenVerified(storage, id, consume)
+}
+
+func GetVerified(storage StorageBackend, id string, verify func([]byte) ([]byte, error)) ([]byte, error) {
+	if getter, ok := storage.(VerifiedGetter); ok {
+		return getter.GetVerified(id, verify)
+	}
+
+	data, err := storage.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return verify(data)
+}
+
+func OpenVerified(storage StorageBackend, id string, consume func(io.Reader) error) error {
+	if getter, ok := storage.(VerifiedGetter); ok {
+		return getter.OpenVerified(id, consume)
+	}
+
+	reader, err := storage.Open(id)
+	if err != nil {
+		return err
+	}
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+
+	return consume(reader)
+}
+
+func (rs *ReplicatedStorage) Name() string {
+	return "replicated"
+}
+
+func (rs *ReplicatedStorage) Backend(name string) StorageBackend {
+	for _, backend := range rs.Backends {
+		if backend.Name() == name {
+			return backend
+		}
+	}
+	return nil
+}
+
+func (rs *ReplicatedStorage) Accepts(id string) bool {
+	return len(rs.locations(id)) > 0
+}
+
+func (rs *ReplicatedStorage) locations(id string) []StorageLocation {
+	var locations []StorageLocation
+	for _, backend := range rs.Backends {
+		if backend.Accepts(id) {
+			locations = append(locations, StorageLocation{Backend: backend.Name(), Id: id})
+		}
+	}
+	return locations
+}
+
+func (rs *ReplicatedStorage) replicate(name string, put func(backend StorageBackend) (UploadResult, error)) (UploadResult, error) {
+	var locations []StorageLocation
+	var failures []error
+	var size int64
+
+	for _, backend := range rs.Backends {
+		result, err := put(backend)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %v", backend.Name(), err))
+			continue
+		}
+
+		size = result.Size
+		locations = append(locations, StorageLocation{Backend: backend.Name(), Id: result.Cid})
+	}
+
+	if len(locations) < rs.MinReplicas {
+		return UploadResult{}, fmt.Errorf("stored %d of %d required replicas: %v", len(locations), rs.MinReplicas, errors.Join(failures...))
+	}
+
+	return UploadResult{Name: name, Cid: locations[0].Id, Size: size, Locations: locations}, nil
+}
+
+func (rs *ReplicatedStorage) Put(name string, data []byte) (UploadResult, error) {
+	return rs.replicate(name, func(backend StorageBackend) (UploadResult, error) {
+		return backend.Put(name, data)
+	})
+}
+
+func (rs *ReplicatedStorage) PutStream(name string, reader io.Reader) (UploadResult, error) {
+	temp, err := os.CreateTemp("", "ccg-replica-*")
+	if err != nil {
+		return UploadResult{}, err
+	}
+	defer func() {
+		_ = temp.Close()
+		_ = os.Remove(temp.Name())
+	}()
+
+	_, err = io.Copy(temp, reader)
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	return rs.replicate(name, func(backend StorageBackend) (UploadResult, error) {
+		_, err := temp.Seek(0, io.SeekStart)
+		if err != nil {
+			return UploadResult{}, err
+		}
+		return backend.PutStream(name, temp)
+	})
+}
+
+func (rs *ReplicatedStorage) Get(id string) ([]byte, error) {
+	return rs.GetVerified(id, func(data []byte) ([]byte, error) {
+		return data, nil
+	})
+}
+
+func (rs *ReplicatedStorage) Open(id string) (io.ReadCloser, error) {
+	var failures []error
+	for _, location := range rs.locations(id) {
+		backend := rs.Backend(location.Backend)
+		if backend == nil {
+			continue
+		}
+
+		reader, err := backend.Open(location.Id)
+		if err == nil {
+			return reader, nil
+		}
+		failures = append(failures, fmt.Errorf("%s: %v", location.Backend, err))
+	}
+
+	return nil, fmt.Errorf("all replicas failed: %v", errors.Join(failures...))
+}
+
+func (rs *ReplicatedStorage) OpenVerified(id string, consume func(io.Reader) error) error {
+	return rs.openVerified(rs.locations(id), consume)
+}
+
+func (rs *ReplicatedStorage) openVerified(locations []StorageLocation, consume func(io.Reader) error) error {
+	if len(locations) == 0 {
+		return errors.New("no storage locations recorded")
+	}
+
+	if !rs.Race {
+		return rs.openInOrder(locations, consume, nil)
+	}
+	return rs.openRace(locations, consume)
+}
+
+func (rs *ReplicatedStorage) openInOrder(locations []StorageLocation, consume func(io.Reader) error, failures []error) error {
+	for _, location := range locations {
+		backend := rs.Backend(location.Backend)
+		if backend == nil {
+			failures = append(failures, fmt.Errorf("%s: backend not configured", location.Backend))
+			continue
+		}
+
+		err := OpenVerified(backend, location.Id, consume)
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, fmt.Errorf("%s: %v", location.Backend, err))
+	}
+
+	return fmt.Errorf("all replicas failed: %v", errors.Join(failures...))
+}
+
+func (rs *ReplicatedStorage) openRace(locations []StorageLocation, consume func(io.Reader) error) error {
+	type opened struct {
+		index   int
+		proceed chan bool
+	}
+	type result struct {
+		index int
+		err   error
+	}
+
+	cancels := make([]context.CancelFunc, len(locations))
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	ready := make(chan opened, len(locations))
+	results := make(chan result, len(locations))
+	for index, location := range locations {
+		var ctx context.Context
+		ctx, cancels[index] = context.WithCancel(context.Background())
+
+		go func(ctx context.Context, index int, location StorageLocation) {
+			backend := rs.Backend(location.Backend)
+			if backend == nil {
+				results <- result{index: index, err: fmt.Errorf("%s: backend not configured", location.Backend)}
+				return
+			}
+
+			err := openContext(ctx, backend, location.Id, func(reader io.Reader) error {
+				buffered := bufio.NewReaderSize(reader, utils.StreamSegmentSize)
+				_, err := buffered.Peek(utils.StreamSegmentSize)
+				if err != nil && !errors.Is(err, io.EOF) {
+					return err
+				}
+
+				proceed := make(chan bool, 1)
+				ready <- opened{index: index, proceed: proceed}
+				select {
+				case won := <-proceed:
+					if !won {
+						return context.Canceled
+					}
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				return consume(buffered)
+			})
+			if err != nil {
+				err = fmt.Errorf("%s: %v", location.Backend, err)
+			}
+			results <- result{index: index, err: err}
+		}(ctx, index, location)
+	}
+
+	var failures []error
+	failed := map[int]bool{}
+	winner := -1
+	for received := 0; received < len(locations); {
+		select {
+		case r := <-ready:
+			if winner != -1 {
+				r.proceed <- false
+				continue
+			}
+
+			winner = r.index
+			for index, cancel := range cancels {
+				if index != winner {
+					cancel()
+				}
+			}
+			r.proceed <- true
+		case r := <-results:
+			received++
+			if winner == -1 {
+				failed[r.index] = true
+				failures = append(failures, r.err)
+				continue
+			}
+			if r.index != winner {
+				continue
+			}
+			if r.err == nil {
+				return nil
+			}
+
+			failures = append(failures, r.err)
+			var remaining []StorageLocation
+			for index, location := range locations {
+				if index != winner && !failed[index] {
+					remaining = append(remaining, location)
+				}
+			}
+			return rs.openInOrder(remaining, consume, failures)
+		}
+	}
+
+	return fmt.Errorf("all replicas failed: %v", errors.Join(failures...))
+}
+
+func openContext(ctx context.Context, storage StorageBackend, id string, consume func(io.Reader) error) error {
+	if opener, ok := storage.(contextOpener); ok {
+		return opener.openVerified(ctx, id, consume)
+	}
+
+	reader, err := storage.Open(id)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = reader.Close()
+	})
+	defer func(reader io.ReadCloser) {
+		if stop() {
+			_ = reader.Close()
+		}
+	}(reader)
+
+	err = consume(reader)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (rs *ReplicatedStorage) fetch(ctx context.Context, location StorageLocation, verify func([]byte) ([]byte, error)) ([]byte, error) {
+	backend := rs.Backend(location.Backend)
+	if backend == nil {
+		return nil, fmt.Errorf("%s: backend not configured", location.Backend)
+	}
+
+	var data []byte
+	err := openContext(ctx, backend, location.Id, func(reader io.Reader) error {
+		var err error
+		data, err = io.ReadAll(reader)
+		return err
+	})
+	if err == nil {
+		data, err = verify(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", location.Backend, err)
+	}
+	return data, nil
+}
+
+func (rs *ReplicatedStorage) GetVerified(id string, verify func([]byte) ([]byte, error)) ([]byte, error) {
+	return rs.getVerified(rs.locations(id), verify)
+}
+
+func (rs *ReplicatedStorage) getVerified(locations []StorageLocation, verify func([]byte) ([]byte, error)) ([]byte, error) {
+	if len(locations) == 0 {
+		return nil, errors.New("no storage locations recorded")
+	}
+
+	var failures []error
+
+	if !rs.Race {
+		for _, location := range locations {
+			data, err := rs.fetch(context.Background(), location, verify)
+			if err == nil {
+				return data, nil
+			}
+			failures = append(failures, err)
+		}
+
+		return nil, fmt.Errorf("all replicas failed: %v", errors.Join(failures...))
+	}
+
+	type result struct {
+		data []byte
+		err  error
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make(chan result, len(locations))
+	for _, location := range locations {
+		go func(location StorageLocation) {
+			data, err := rs.fetch(ctx, location, verify)
+			results <- result{data: data, err: err}
+		}(location)
+	}
+
+	for range locations {
+		r := <-results
+		if r.err == nil {
+			return r.data, nil
+		}
+		failures = append(failures, r.err)
+	}
+
+	return nil, fmt.Errorf("all replicas failed: %v", errors.Join(failures...))
+}
+
+func (rs *ReplicatedStorage) Stat(id string) (StorageStat, error) {
+	var failures []error
+	for _, location := range rs.locations(id) {
+		backend := rs.Backend(location.Backend)
+		if backend == nil {
+			continue
+		}
+
+		stat, err := backend.Stat(location.Id)
+		if err == nil {
+			return stat, nil
+		}
+		failures = append(failures, fmt.Errorf("%s: %v", location.Backend, err))
+	}
+
+	return StorageStat{}, fmt.Errorf("no replica could be inspected: %v", errors.Join(failures...))
+}
+
+func (rs *ReplicatedStorage) Delete(id string) error {
+	var failures []error
+	for _, location := range rs.locations(id) {
+		backend := rs.Backend(location.Backend)
+		if backend == nil {
+			continue
+		}
+
+		err := backend.Delete(location.Id)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %v", location.Backend, err))
+		}
+	}
+
+	return errors.Join(failures...)
+}
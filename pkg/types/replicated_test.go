@@ -0,0 +1,125 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type gatedBackend struct {
+	StorageBackend
+	gate chan struct{}
+}
+
+func (gb gatedBackend) Open(id string) (io.ReadCloser, error) {
+	<-gb.gate
+	return gb.StorageBackend.Open(id)
+}
+
+func stalledLighthouse(t *testing.T, data []byte) (*LighthouseClient, chan struct{}, chan struct{}) {
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data[:10])
+		w.(http.Flusher).Flush()
+		close(started)
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	t.Cleanup(gateway.Close)
+
+	return &LighthouseClient{Gateways: &GatewayPool{Gateways: []Gateway{{Url: gateway.URL}}, Client: http.DefaultClient}}, started, cancelled
+}
+
+func TestReplicatedRaceCancelsLosers(t *testing.T) {
+	_, kubo := newKuboStandIn(t)
+	data := []byte(archivePayload)
+	result, err := kubo.Put("repo.git", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stalled, started, cancelled := stalledLighthouse(t, data)
+	replicated := &ReplicatedStorage{Backends: []StorageBackend{stalled, gatedBackend{kubo, started}}, Race: true}
+	downloaded, err := replicated.Get(result.Cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatal("downloaded content differs from the upload")
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("losing replica was not cancelled")
+	}
+}
+
+func TestReplicatedOpenRaceCancelsLosers(t *testing.T) {
+	_, kubo := newKuboStandIn(t)
+	data := []byte(archivePayload)
+	result, err := kubo.Put("repo.git", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stalled, started, cancelled := stalledLighthouse(t, data)
+	replicated := &ReplicatedStorage{Backends: []StorageBackend{stalled, gatedBackend{kubo, started}}, Race: true}
+	locations := []StorageLocation{{Backend: "lighthouse", Id: result.Cid}, {Backend: "kubo", Id: result.Cid}}
+
+	var downloaded []byte
+	err = OpenLocations(replicated, locations, func(reader io.Reader) error {
+		downloaded, err = io.ReadAll(reader)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatal("downloaded content differs from the upload")
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("losing replica was not cancelled")
+	}
+}
+
+func TestReplicatedOpenRaceFallsBack(t *testing.T) {
+	_, kubo := newKuboStandIn(t)
+	local := &LocalStorage{Directory: t.TempDir()}
+	data := []byte(archivePayload)
+
+	replicated := &ReplicatedStorage{Backends: []StorageBackend{kubo, local}, Race: true}
+	result, err := replicated.Put("repo.git", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rejected := errors.New("wrong key")
+	var attempts int
+	var downloaded []byte
+	err = OpenLocations(replicated, result.Locations, func(reader io.Reader) error {
+		attempts++
+		if attempts == 1 {
+			return rejected
+		}
+		downloaded, err = io.ReadAll(reader)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 || !bytes.Equal(downloaded, data) {
+		t.Fatalf("expected a second replica to be consumed, got %d attempts", attempts)
+	}
+}
@@ -0,0 +1,403 @@
+package types
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+const s3MaxParts = 10000
+
+var (
+	s3PartSize           = 64 << 20
+	s3CopyPartSize int64 = 1 << 30
+)
+
+type S3Client struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	Client    *http.Client
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func s3Escape(path string) string {
+	var builder strings.Builder
+	for _, b := range []byte(path) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/':
+			builder.WriteByte(b)
+		default:
+			_, _ = fmt.Fprintf(&builder, "%%%02X", b)
+		}
+	}
+	return builder.String()
+}
+
+func (s3 *S3Client) Name() string {
+	return "s3"
+}
+
+func (s3 *S3Client) Accepts(id string) bool {
+	return s3.validKey(id) == nil
+}
+
+func (s3 *S3Client) validKey(id string) error {
+	key := strings.TrimPrefix(id, s3.Prefix)
+	decoded, err := hex.DecodeString(key)
+	if err != nil || len(decoded) != sha256.Size || !strings.HasPrefix(id, s3.Prefix) {
+		return fmt.Errorf("invalid s3 object key: %q", id)
+	}
+	return nil
+}
+
+func (s3 *S3Client) sign(req *http.Request, payloadHash string) {
+	s3.signAt(req, payloadHash, time.Now().UTC())
+}
+
+func (s3 *S3Client) signAt(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		if name = strings.ToLower(name); strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path),
+		strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s3.Region)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s3.SecretKey), date)
+	signingKey = hmacSHA256(signingKey, s3.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3.AccessKey, scope, signedHeaders, signature))
+}
+
+func (s3 *S3Client) request(method string, key string, body io.Reader, length int64, payloadHash string) (*http.Response, error) {
+	return s3.requestWith(method, key, nil, nil, body, length, payloadHash)
+}
+
+func (s3 *S3Client) requestWith(method string, key string, query url.Values, headers map[string]string, body io.Reader, length int64, payloadHash string) (*http.Response, error) {
+	endpoint, err := url.Parse(strings.TrimRight(s3.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %v", err)
+	}
+	endpoint.Path = fmt.Sprintf("%s/%s/%s", endpoint.Path, s3.Bucket, key)
+	endpoint.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	req, err := http.NewRequest(method, endpoint.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if body != nil {
+		req.ContentLength = length
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	s3.sign(req, payloadHash)
+
+	resp, err := s3.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach s3 endpoint: %v", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer func(Body io.ReadCloser) {
+			_ = Body.Close()
+		}(resp.Body)
+
+		message, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("s3 %s %s error (%s): %s", method, key, resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	return resp, nil
+}
+
+func (s3 *S3Client) Put(name string, data []byte) (UploadResult, error) {
+	return s3.PutStream(name, bytes.NewReader(data))
+}
+
+func (s3 *S3Client) PutStream(name string, reader io.Reader) (UploadResult, error) {
+	part := make([]byte, s3PartSize)
+	n, err := io.ReadFull(reader, part)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return s3.putObject(name, part[:n])
+	}
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	return s3.putMultipart(name, part, reader)
+}
+
+func (s3 *S3Client) putObject(name string, data []byte) (UploadResult, error) {
+	digest := sha256.Sum256(data)
+	payloadHash := hex.EncodeToString(digest[:])
+	key := s3.Prefix + payloadHash
+
+	resp, err := s3.request("PUT", key, bytes.NewReader(data), int64(len(data)), payloadHash)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	_ = resp.Body.Close()
+
+	return UploadResult{Name: name, Cid: key, Size: int64(len(data))}, nil
+}
+
+type s3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3Response struct {
+	UploadId string `xml:"UploadId"`
+	ETag     string `xml:"ETag"`
+	Code     string `xml:"Code"`
+	Message  string `xml:"Message"`
+}
+
+func (s3 *S3Client) call(method string, key string, query url.Values, headers map[string]string, body []byte) (s3Response, http.Header, error) {
+	digest := sha256.Sum256(body)
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	resp, err := s3.requestWith(method, key, query, headers, reader, int64(len(body)), hex.EncodeToString(digest[:]))
+	if err != nil {
+		return s3Response{}, nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	var result s3Response
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return s3Response{}, nil, err
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		err = xml.Unmarshal(data, &result)
+		if err != nil {
+			return s3Response{}, nil, fmt.Errorf("invalid s3 response: %v", err)
+		}
+	}
+	if result.Code != "" {
+		return s3Response{}, nil, fmt.Errorf("s3 %s %s error (%s): %s", method, key, result.Code, result.Message)
+	}
+	return result, resp.Header, nil
+}
+
+func (s3 *S3Client) multipart(key string, upload func(uploadId string) ([]s3Part, error)) error {
+	created, _, err := s3.call("POST", key, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	parts, err := upload(created.UploadId)
+	if err != nil {
+		_, _, _ = s3.call("DELETE", key, url.Values{"uploadId": {created.UploadId}}, nil, nil)
+		return err
+	}
+
+	completion, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	_, _, err = s3.call("POST", key, url.Values{"uploadId": {created.UploadId}}, nil, completion)
+	if err != nil {
+		_, _, _ = s3.call("DELETE", key, url.Values{"uploadId": {created.UploadId}}, nil, nil)
+	}
+	return err
+}
+
+func (s3 *S3Client) putMultipart(name string, first []byte, reader io.Reader) (UploadResult, error) {
+	staging := make([]byte, 16)
+	_, err := rand.Read(staging)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	stagingKey := s3.Prefix + "incoming/" + hex.EncodeToString(staging)
+
+	hash := sha256.New()
+	var length int64
+	err = s3.multipart(stagingKey, func(uploadId string) ([]s3Part, error) {
+		var parts []s3Part
+		part := first
+		for number := 1; len(part) > 0; number++ {
+			if number > s3MaxParts {
+				return nil, fmt.Errorf("archive exceeds %d parts of %d bytes", s3MaxParts, s3PartSize)
+			}
+
+			hash.Write(part)
+			length += int64(len(part))
+
+			query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadId}}
+			_, header, err := s3.call("PUT", stagingKey, query, nil, part)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, s3Part{PartNumber: number, ETag: header.Get("ETag")})
+
+			part = first[:s3PartSize]
+			n, err := io.ReadFull(reader, part)
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				err = nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			part = part[:n]
+		}
+		return parts, nil
+	})
+	if err != nil {
+		return UploadResult{}, err
+	}
+	defer func() {
+		_, _, _ = s3.call("DELETE", stagingKey, nil, nil, nil)
+	}()
+
+	key := s3.Prefix + hex.EncodeToString(hash.Sum(nil))
+	source := "/" + s3.Bucket + "/" + s3Escape(stagingKey)
+	err = s3.multipart(key, func(uploadId string) ([]s3Part, error) {
+		var parts []s3Part
+		for offset, number := int64(0), 1; offset < length; offset, number = offset+s3CopyPartSize, number+1 {
+			headers := map[string]string{
+				"X-Amz-Copy-Source":       source,
+				"X-Amz-Copy-Source-Range": fmt.Sprintf("bytes=%d-%d", offset, min(offset+s3CopyPartSize, length)-1),
+			}
+
+			query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadId}}
+			copied, _, err := s3.call("PUT", key, query, headers, nil)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, s3Part{PartNumber: number, ETag: copied.ETag})
+		}
+		return parts, nil
+	})
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	return UploadResult{Name: name, Cid: key, Size: length}, nil
+}
+
+func (s3 *S3Client) Get(id string) ([]byte, error) {
+	return readAll(s3.Open(id))
+}
+
+func (s3 *S3Client) Open(id string) (io.ReadCloser, error) {
+	err := s3.validKey(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s3.request("GET", id, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := strings.TrimPrefix(id, s3.Prefix)
+	return newDigestReader(resp.Body, digest, fmt.Errorf("s3 returned wrong content for %s", id)), nil
+}
+
+func (s3 *S3Client) Stat(id string) (StorageStat, error) {
+	err := s3.validKey(id)
+	if err != nil {
+		return StorageStat{}, err
+	}
+
+	resp, err := s3.request("HEAD", id, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return StorageStat{}, err
+	}
+	_ = resp.Body.Close()
+
+	return StorageStat{
+		Id:     id,
+		Size:   resp.ContentLength,
+		Pinned: true,
+	}, nil
+}
+
+func (s3 *S3Client) Delete(id string) error {
+	err := s3.validKey(id)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s3.request("DELETE", id, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
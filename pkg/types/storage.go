@@ -0,0 +1,166 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime/multipart"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+)
+
+type UploadResult struct {
+	Name string `json:"name"`
+	Cid  string `json:"cid"`
+	Size int64  `json:"size"`
+
+	Locations []StorageLocation `json:"locations,omitempty"`
+}
+
+type ipfsAddResponse struct {
+	Name string      `json:"Name"`
+	Hash string      `json:"Hash"`
+	Size json.Number `json:"Size"`
+}
+
+func (response ipfsAddResponse) result() (UploadResult, error) {
+	if response.Hash == "" {
+		return UploadResult{}, fmt.Errorf("no hash returned for %s", response.Name)
+	}
+
+	size, err := strconv.ParseInt(response.Size.String(), 10, 64)
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("invalid size %q returned for %s", response.Size, response.Name)
+	}
+
+	return UploadResult{
+		Name: response.Name,
+		Cid:  response.Hash,
+		Size: size,
+	}, nil
+}
+
+type dagImportResponse struct {
+	Root struct {
+		Cid struct {
+			Link string `json:"/"`
+		} `json:"Cid"`
+		PinErrorMsg string `json:"PinErrorMsg"`
+	} `json:"Root"`
+	Hash string `json:"Hash"`
+}
+
+func (response dagImportResponse) result(name string, root string) (UploadResult, error) {
+	if response.Root.PinErrorMsg != "" {
+		return UploadResult{}, fmt.Errorf("failed to pin %s: %s", root, response.Root.PinErrorMsg)
+	}
+
+	imported := response.Root.Cid.Link
+	if imported == "" {
+		imported = response.Hash
+	}
+	if imported != "" && imported != root {
+		return UploadResult{}, fmt.Errorf("car root %s imported as %s", root, imported)
+	}
+
+	return UploadResult{Name: name, Cid: root}, nil
+}
+
+type StorageStat struct {
+	Id     string
+	Size   int64
+	Pinned bool
+}
+
+type StorageBackend interface {
+	Name() string
+	Accepts(id string) bool
+	Put(name string, data []byte) (UploadResult, error)
+	PutStream(name string, reader io.Reader) (UploadResult, error)
+	Get(id string) ([]byte, error)
+	Open(id string) (io.ReadCloser, error)
+	Stat(id string) (StorageStat, error)
+	Delete(id string) error
+}
+
+type CarImporter interface {
+	Has(root string) bool
+	PutCar(name string, root string, reader io.Reader) (UploadResult, error)
+}
+
+func isCid(id string) bool {
+	_, err := cid.Decode(id)
+	return err == nil
+}
+
+func readAll(reader io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return data, nil
+}
+
+type digestReader struct {
+	reader io.ReadCloser
+	hash   hash.Hash
+	digest string
+	err    error
+}
+
+func newDigestReader(reader io.ReadCloser, digest string, err error) io.ReadCloser {
+	return &digestReader{
+		reader: reader,
+		hash:   sha256.New(),
+		digest: digest,
+		err:    err,
+	}
+}
+
+func (dr *digestReader) Read(p []byte) (int, error) {
+	n, err := dr.reader.Read(p)
+	dr.hash.Write(p[:n])
+	if errors.Is(err, io.EOF) && hex.EncodeToString(dr.hash.Sum(nil)) != dr.digest {
+		return n, dr.err
+	}
+	return n, err
+}
+
+func (dr *digestReader) Close() error {
+	return dr.reader.Close()
+}
+
+func multipartStream(name string, reader io.Reader) (io.ReadCloser, string) {
+	pipeReader, pipeWriter := io.Pipe()
+	writer := multipart.NewWriter(pipeWriter)
+
+	go func() {
+		part, err := writer.CreateFormFile("file", name)
+		if err != nil {
+			_ = pipeWriter.CloseWithError(err)
+			return
+		}
+
+		_, err = io.Copy(part, reader)
+		if err != nil {
+			_ = pipeWriter.CloseWithError(err)
+			return
+		}
+
+		_ = pipeWriter.CloseWithError(writer.Close())
+	}()
+
+	return pipeReader, writer.FormDataContentType()
+}
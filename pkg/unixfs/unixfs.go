@@ -0,0 +1,277 @@
+package unixfs
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+const (
+	ChunkSize    = 256 * 1024
+	LinksPerNode = 174
+
+	unixfsFile = 2
+)
+
+var ErrWrongContent = errors.New("gateway returned wrong content")
+
+type link struct {
+	cid      cid.Cid
+	fileSize uint64
+	tSize    uint64
+}
+
+type Builder struct {
+	Version   uint64
+	RawLeaves bool
+	OnBlock   func(c cid.Cid, block []byte) error
+
+	buffer []byte
+	levels [][]link
+	leaves int
+}
+
+func NewBuilder(version uint64, rawLeaves bool) *Builder {
+	return &Builder{
+		Version:   version,
+		RawLeaves: rawLeaves,
+		buffer:    make([]byte, 0, ChunkSize),
+	}
+}
+
+func appendVarint(buf []byte, value uint64) []byte {
+	for value >= 0x80 {
+		buf = append(buf, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(buf, byte(value))
+}
+
+func appendVarintField(buf []byte, field int, value uint64) []byte {
+	buf = appendVarint(buf, uint64(field<<3))
+	return appendVarint(buf, value)
+}
+
+func appendBytesField(buf []byte, field int, value []byte) []byte {
+	buf = appendVarint(buf, uint64(field<<3|2))
+	buf = appendVarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+func (b *Builder) block(codec uint64, data []byte) (cid.Cid, error) {
+	sum := sha256.Sum256(data)
+	hash, err := multihash.Encode(sum[:], multihash.SHA2_256)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	var c cid.Cid
+	if b.Version == 0 {
+		c = cid.NewCidV0(hash)
+	} else {
+		c = cid.NewCidV1(codec, hash)
+	}
+
+	if b.OnBlock != nil {
+		err = b.OnBlock(c, data)
+		if err != nil {
+			return cid.Undef, err
+		}
+	}
+	return c, nil
+}
+
+func (b *Builder) leaf(data []byte) (link, error) {
+	if b.RawLeaves {
+		c, err := b.block(cid.Raw, data)
+		return link{cid: c, fileSize: uint64(len(data)), tSize: uint64(len(data))}, err
+	}
+
+	fsNode := appendVarintField(nil, 1, unixfsFile)
+	if len(data) > 0 {
+		fsNode = appendBytesField(fsNode, 2, data)
+	}
+	fsNode = appendVarintField(fsNode, 3, uint64(len(data)))
+
+	node := appendBytesField(nil, 1, fsNode)
+	c, err := b.block(cid.DagProtobuf, node)
+	return link{cid: c, fileSize: uint64(len(data)), tSize: uint64(len(node))}, err
+}
+
+func (b *Builder) node(children []link) (link, error) {
+	var node []byte
+	var fileSize, tSize uint64
+
+	fsNode := appendVarintField(nil, 1, unixfsFile)
+	for _, child := range children {
+		fileSize += child.fileSize
+		tSize += child.tSize
+	}
+	fsNode = appendVarintField(fsNode, 3, fileSize)
+	for _, child := range children {
+		fsNode = appendVarintField(fsNode, 4, child.fileSize)
+	}
+
+	for _, child := range children {
+		pbLink := appendBytesField(nil, 1, child.cid.Bytes())
+		pbLink = appendBytesField(pbLink, 2, nil)
+		pbLink = appendVarintField(pbLink, 3, child.tSize)
+		node = appendBytesField(node, 2, pbLink)
+	}
+	node = appendBytesField(node, 1, fsNode)
+
+	c, err := b.block(cid.DagProtobuf, node)
+	return link{cid: c, fileSize: fileSize, tSize: tSize + uint64(len(node))}, err
+}
+
+func (b *Builder) push(level int, entry link) error {
+	if level == len(b.levels) {
+		b.levels = append(b.levels, nil)
+	}
+	b.levels[level] = append(b.levels[level], entry)
+
+	if len(b.levels[level]) < LinksPerNode {
+		return nil
+	}
+
+	parent, err := b.node(b.levels[level])
+	if err != nil {
+		return err
+	}
+	b.levels[level] = nil
+	return b.push(level+1, parent)
+}
+
+func (b *Builder) flushChunk() error {
+	entry, err := b.leaf(b.buffer)
+	if err != nil {
+		return err
+	}
+
+	b.buffer = b.buffer[:0]
+	b.leaves++
+	return b.push(0, entry)
+}
+
+func (b *Builder) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if len(b.buffer) == ChunkSize {
+			err := b.flushChunk()
+			if err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(b.buffer[len(b.buffer):ChunkSize], p)
+		b.buffer = b.buffer[:len(b.buffer)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (b *Builder) Sum() (cid.Cid, error) {
+	if len(b.buffer) > 0 || b.leaves == 0 {
+		err := b.flushChunk()
+		if err != nil {
+			return cid.Undef, err
+		}
+	}
+
+	for level := 0; level < len(b.levels); level++ {
+		entries := b.levels[level]
+		if len(entries) == 0 {
+			continue
+		}
+
+		highest := true
+		for _, above := range b.levels[level+1:] {
+			if len(above) > 0 {
+				highest = false
+				break
+			}
+		}
+		if highest && len(entries) == 1 {
+			return entries[0].cid, nil
+		}
+
+		parent, err := b.node(entries)
+		if err != nil {
+			return cid.Undef, err
+		}
+		b.levels[level] = nil
+		if level+1 == len(b.levels) {
+			b.levels = append(b.levels, nil)
+		}
+		b.levels[level+1] = append(b.levels[level+1], parent)
+	}
+
+	return cid.Undef, errors.New("empty dag")
+}
+
+func Compute(reader io.Reader, version uint64, rawLeaves bool) (cid.Cid, error) {
+	builder := NewBuilder(version, rawLeaves)
+	_, err := io.Copy(builder, reader)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return builder.Sum()
+}
+
+type verifyingReader struct {
+	reader   io.ReadCloser
+	expected cid.Cid
+	builders []*Builder
+}
+
+func NewVerifyingReader(reader io.ReadCloser, expected string) (io.ReadCloser, error) {
+	c, err := cid.Decode(expected)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cid %q: %v", expected, err)
+	}
+
+	var builders []*Builder
+	switch {
+	case c.Version() == 0:
+		builders = []*Builder{NewBuilder(0, false)}
+	case c.Type() == cid.Raw:
+		builders = []*Builder{NewBuilder(1, true)}
+	case c.Type() == cid.DagProtobuf:
+		builders = []*Builder{NewBuilder(1, true), NewBuilder(1, false)}
+	default:
+		return nil, fmt.Errorf("cannot verify cid %s with codec %x", expected, c.Type())
+	}
+
+	return &verifyingReader{
+		reader:   reader,
+		expected: c,
+		builders: builders,
+	}, nil
+}
+
+func (vr *verifyingReader) Read(p []byte) (int, error) {
+	n, err := vr.reader.Read(p)
+	for _, builder := range vr.builders {
+		_, _ = builder.Write(p[:n])
+	}
+
+	if errors.Is(err, io.EOF) {
+		for _, builder := range vr.builders {
+			actual, sumErr := builder.Sum()
+			if sumErr == nil && actual.Equals(vr.expected) {
+				return n, err
+			}
+		}
+		return n, fmt.Errorf("%w: expected %s", ErrWrongContent, vr.expected)
+	}
+	return n, err
+}
+
+func (vr *verifyingReader) Close() error {
+	return vr.reader.Close()
+}
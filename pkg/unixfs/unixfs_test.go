@@ -0,0 +1,159 @@
+package unixfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func payload(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i*7 + i/251)
+	}
+	return data
+}
+
+// CIDs printed by kubo v0.32.1 for `ipfs add --only-hash`, `--cid-version=1`
+// (raw leaves) and `--cid-version=1 --raw-leaves=false`.
+var knownCids = []struct {
+	name       string
+	data       []byte
+	v0         string
+	v1         string
+	v1Protobuf string
+}{
+	{
+		name:       "empty",
+		data:       payload(0),
+		v0:         "QmbFMke1KXqnYyBBWxB74N4c5SBnJMVAiMNRcGu6x1AwQH",
+		v1:         "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku",
+		v1Protobuf: "bafybeif7ztnhq65lumvvtr4ekcwd2ifwgm3awq4zfr3srh462rwyinlb4y",
+	},
+	{
+		name:       "hello world",
+		data:       []byte("hello world"),
+		v0:         "Qmf412jQZiuVUtdgnB36FXFX7xg5V6KEbSJ4dpQuhkLyfD",
+		v1:         "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e",
+		v1Protobuf: "bafybeihykld7uyxzogax6vgyvag42y7464eywpf55gxi5qpoisibh3c5wa",
+	},
+	{
+		name:       "single chunk",
+		data:       payload(1000),
+		v0:         "QmZ3TLDhQz2mH6d99AyBHduHVzW6xpq3CZUT68gJiC9BFZ",
+		v1:         "bafkreia5tvdrsj5w6df4o6xnrfu57rxpip7tl7on3cvaamtdbqagh74vuu",
+		v1Protobuf: "bafybeie7bdiwstdge5f2hwbzvkf64r26v32l3h7u3hae2d5wefnlkq7laq",
+	},
+	{
+		name:       "exactly one chunk",
+		data:       payload(ChunkSize),
+		v0:         "QmPDdohMbmJheh372VbUUXsZ7PD68Xgs2oqbHSyETSmVft",
+		v1:         "bafkreidtpmdhdmgsrvgltsrlzrfs5ragszmg3o3legvfagtwldoeiucayu",
+		v1Protobuf: "bafybeianb6mxwhdgemzb2hn3x3gzcjtlqbod4e7bkeunuykxcxybrhzov4",
+	},
+	{
+		name:       "one chunk and a byte",
+		data:       payload(ChunkSize + 1),
+		v0:         "QmVV8Xo8bfqTCYSYKMoQoDNCbtzujHGfAxvJEJsm7QP6Fw",
+		v1:         "bafybeidbuw2hpnkpnbwvz6xirywx74hyz64czv2fhdsr2m2c67bbpsd3m4",
+		v1Protobuf: "bafybeih4pbmlrvzwajb4evjnqzfkqqjuoltqchubvv4weznb5f2ia6z6hi",
+	},
+	{
+		name:       "two levels",
+		data:       payload((LinksPerNode+1)*ChunkSize + 1),
+		v0:         "QmNtSLyEswTCed8FdRPZkbsGSbf1UWzPQ92jWQkgGwWTEw",
+		v1:         "bafybeif7to3x5a3p27kfmoagp22z7wc4kiid35k2qzv4de32wyxaorbbxy",
+		v1Protobuf: "bafybeiczj4pawks2jhxjjos5hvqkrzgu2klzp7si6pgmzlov55j4g5bm6a",
+	},
+}
+
+func TestComputeKnownCids(t *testing.T) {
+	for _, known := range knownCids {
+		for _, expected := range []struct {
+			version   uint64
+			rawLeaves bool
+			cid       string
+		}{{0, false, known.v0}, {1, true, known.v1}, {1, false, known.v1Protobuf}} {
+			actual, err := Compute(bytes.NewReader(known.data), expected.version, expected.rawLeaves)
+			if err != nil {
+				t.Fatalf("%s: %v", known.name, err)
+			}
+			if actual.String() != expected.cid {
+				t.Fatalf("%s: cid v%d raw leaves %v is %s, expected %s", known.name, expected.version, expected.rawLeaves, actual, expected.cid)
+			}
+		}
+	}
+}
+
+func TestComputeSplitWrites(t *testing.T) {
+	known := knownCids[4]
+	builder := NewBuilder(0, false)
+	for offset := 0; offset < len(known.data); offset += 1000 {
+		_, err := builder.Write(known.data[offset:min(offset+1000, len(known.data))])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actual, err := builder.Sum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.String() != known.v0 {
+		t.Fatalf("cid is %s, expected %s", actual, known.v0)
+	}
+}
+
+func TestVerifyingReader(t *testing.T) {
+	for _, known := range knownCids {
+		for _, expected := range []string{known.v0, known.v1, known.v1Protobuf} {
+			reader, err := NewVerifyingReader(io.NopCloser(bytes.NewReader(known.data)), expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("%s: %s rejected: %v", known.name, expected, err)
+			}
+			if !bytes.Equal(data, known.data) {
+				t.Fatalf("%s: content differs", known.name)
+			}
+		}
+	}
+}
+
+func TestVerifyingReaderRejectsWrongContent(t *testing.T) {
+	for _, known := range knownCids[1:] {
+		tampered := append([]byte{}, known.data...)
+		tampered[len(tampered)-1] ^= 1
+
+		for _, content := range [][]byte{tampered, known.data[:len(known.data)-1], append(append([]byte{}, known.data...), 0)} {
+			for _, expected := range []string{known.v0, known.v1, known.v1Protobuf} {
+				reader, err := NewVerifyingReader(io.NopCloser(bytes.NewReader(content)), expected)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				_, err = io.ReadAll(reader)
+				if !errors.Is(err, ErrWrongContent) {
+					t.Fatalf("%s: %s accepted wrong content: %v", known.name, expected, err)
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyingReaderInvalidCid(t *testing.T) {
+	_, err := NewVerifyingReader(io.NopCloser(bytes.NewReader(nil)), "not-a-cid")
+	if err == nil {
+		t.Fatal("invalid cid was accepted")
+	}
+
+	_, err = NewVerifyingReader(io.NopCloser(bytes.NewReader(nil)), "bafyreigbtj4x7ip5legnfznufuopl4sg4knzc2cof6duas4b3q2fy6swua")
+	if err == nil || !strings.Contains(err.Error(), "codec") {
+		t.Fatal("dag-cbor cid was accepted")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base32"
@@ -14,29 +15,52 @@ import (
 	"strings"
 )
 
+func convergentNonce(key []byte, domain string, data []byte, size int) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("ccg convergent " + domain + "\x00"))
+	mac.Write(data)
+	return mac.Sum(nil)[:size]
+}
+
 func Encrypt(key, plaintext []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
+	nonce := make([]byte, 12)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, err
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	return seal(key, nonce, plaintext)
+}
+
+func EncryptConvergent(key, plaintext []byte) ([]byte, error) {
+	return seal(key, convergentNonce(key, "buffer", plaintext, 12), plaintext)
+}
+
+func seal(key, nonce, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
 		return nil, err
 	}
 
-	data := append(plaintext, key...)
+	data := append(append([]byte{}, plaintext...), key...)
 
 	ciphertext := gcm.Seal(nonce, nonce, data, nil)
 	return ciphertext, nil
 }
 
 func Decrypt(key, ciphertext []byte) ([]byte, error) {
+	if IsStream(ciphertext) {
+		reader, err := NewDecryptReader(key, bytes.NewReader(ciphertext))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(reader)
+	}
+
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
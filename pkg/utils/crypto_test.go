@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestEncryptRoundTrip(t *testing.T) {
+	for _, encrypt := range []func(key, plaintext []byte) ([]byte, error){Encrypt, EncryptConvergent} {
+		cipherBuf, err := encrypt(streamKey, []byte("metadata"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		plain, err := Decrypt(streamKey, cipherBuf)
+		if err != nil || string(plain) != "metadata" {
+			t.Fatalf("unexpected plaintext %q: %v", plain, err)
+		}
+
+		_, err = Decrypt(bytes.Repeat([]byte{0x24}, 32), cipherBuf)
+		if err == nil {
+			t.Fatal("wrong key was accepted")
+		}
+	}
+}
+
+func TestEncryptIsRandomized(t *testing.T) {
+	first, err := Encrypt(streamKey, []byte("metadata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Encrypt(streamKey, []byte("metadata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, second) {
+		t.Fatal("random nonces produced identical ciphertexts")
+	}
+}
+
+func TestEncryptConvergentIsDeterministic(t *testing.T) {
+	first, err := EncryptConvergent(streamKey, []byte("metadata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := EncryptConvergent(streamKey, []byte("metadata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatal("convergent encryption of the same plaintext differs")
+	}
+
+	other, err := EncryptConvergent(streamKey, []byte("metadatb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first[:12], other[:12]) {
+		t.Fatal("different plaintexts share a nonce")
+	}
+
+	otherKey, err := EncryptConvergent(bytes.Repeat([]byte{0x24}, 32), []byte("metadata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, otherKey) {
+		t.Fatal("different keys produced identical ciphertexts")
+	}
+}
+
+func TestConvergentStreamIsDeterministic(t *testing.T) {
+	plain := bytes.Repeat([]byte("archive"), 2*StreamSegmentSize/7)
+	digest := sha256.Sum256(plain)
+
+	encrypt := func(data []byte, digest []byte) []byte {
+		var cipherBuf bytes.Buffer
+		writer, err := NewConvergentEncryptWriter(streamKey, digest, &cipherBuf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = writer.Write(data)
+		if err == nil {
+			err = writer.Close()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cipherBuf.Bytes()
+	}
+
+	first := encrypt(plain, digest[:])
+	if !bytes.Equal(first, encrypt(plain, digest[:])) {
+		t.Fatal("convergent stream encryption of the same archive differs")
+	}
+
+	decrypted, err := decryptStream(streamKey, first)
+	if err != nil || !bytes.Equal(decrypted, plain) {
+		t.Fatalf("convergent stream did not round trip: %v", err)
+	}
+
+	otherPlain := append([]byte{}, plain...)
+	otherPlain[0] ^= 1
+	otherDigest := sha256.Sum256(otherPlain)
+	header, _ := segments(first)
+	otherHeader, _ := segments(encrypt(otherPlain, otherDigest[:]))
+	if bytes.Equal(header, otherHeader) {
+		t.Fatal("different archives share a nonce prefix")
+	}
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type idleReader struct {
+	reader  io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+
+	mutex   sync.Mutex
+	stalled bool
+}
+
+func NewIdleReader(reader io.ReadCloser, timeout time.Duration) io.ReadCloser {
+	if timeout <= 0 {
+		return reader
+	}
+
+	idle := &idleReader{reader: reader, timeout: timeout}
+	idle.timer = time.AfterFunc(timeout, func() {
+		idle.mutex.Lock()
+		idle.stalled = true
+		idle.mutex.Unlock()
+		_ = reader.Close()
+	})
+	idle.timer.Stop()
+	return idle
+}
+
+func (ir *idleReader) Read(p []byte) (int, error) {
+	ir.timer.Reset(ir.timeout)
+	n, err := ir.reader.Read(p)
+	ir.timer.Stop()
+
+	ir.mutex.Lock()
+	defer ir.mutex.Unlock()
+	if ir.stalled {
+		return n, fmt.Errorf("no data received for %v", ir.timeout)
+	}
+	return n, err
+}
+
+func (ir *idleReader) Close() error {
+	ir.timer.Stop()
+	return ir.reader.Close()
+}
+
+type idleTransport struct {
+	http.RoundTripper
+	timeout time.Duration
+}
+
+func (it idleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := it.RoundTripper.RoundTrip(req)
+	if err == nil {
+		resp.Body = NewIdleReader(resp.Body, it.timeout)
+	}
+	return resp, err
+}
+
+func NewStreamingClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: idleTransport{
+			RoundTripper: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   timeout,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				ExpectContinueTimeout: time.Second,
+				IdleConnTimeout:       90 * time.Second,
+			},
+			timeout: timeout,
+		},
+	}
+}
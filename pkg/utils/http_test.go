@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type slowReader struct {
+	reader io.Reader
+	delay  time.Duration
+}
+
+func (sr slowReader) Read(p []byte) (int, error) {
+	time.Sleep(sr.delay)
+	return sr.reader.Read(p)
+}
+
+func TestIdleReaderIgnoresConsumerTime(t *testing.T) {
+	reader := NewIdleReader(io.NopCloser(strings.NewReader("archive")), 50*time.Millisecond)
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+
+	buffer := make([]byte, 1)
+	for {
+		_, err := reader.Read(buffer)
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestIdleReaderStalled(t *testing.T) {
+	reader := NewIdleReader(io.NopCloser(slowReader{reader: strings.NewReader("archive"), delay: 200 * time.Millisecond}), 50*time.Millisecond)
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+
+	_, err := reader.Read(make([]byte, 16))
+	if err == nil || !strings.Contains(err.Error(), "no data received") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	StreamSegmentSize = 64 * 1024
+	streamPrefixSize  = 7
+)
+
+var streamMagic = []byte("CCGSTRM1")
+
+type encryptWriter struct {
+	gcm     cipher.AEAD
+	writer  io.Writer
+	prefix  []byte
+	counter uint32
+	buffer  []byte
+	closed  bool
+}
+
+type decryptReader struct {
+	gcm     cipher.AEAD
+	reader  *bufio.Reader
+	prefix  []byte
+	counter uint32
+	segment []byte
+	plain   []byte
+	done    bool
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func streamNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, streamPrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[streamPrefixSize:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+func IsStream(ciphertext []byte) bool {
+	return bytes.HasPrefix(ciphertext, streamMagic)
+}
+
+func NewEncryptWriter(key []byte, writer io.Writer) (io.WriteCloser, error) {
+	prefix := make([]byte, streamPrefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+
+	return newEncryptWriter(key, prefix, writer)
+}
+
+func NewConvergentEncryptWriter(key []byte, digest []byte, writer io.Writer) (io.WriteCloser, error) {
+	return newEncryptWriter(key, convergentNonce(key, "stream", digest, streamPrefixSize), writer)
+}
+
+func newEncryptWriter(key []byte, prefix []byte, writer io.Writer) (io.WriteCloser, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = writer.Write(append(append([]byte{}, streamMagic...), prefix...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{
+		gcm:    gcm,
+		writer: writer,
+		prefix: prefix,
+		buffer: make([]byte, 0, StreamSegmentSize),
+	}, nil
+}
+
+func (ew *encryptWriter) seal(last bool) error {
+	if ew.counter == ^uint32(0) {
+		return errors.New("stream too long")
+	}
+
+	sealed := ew.gcm.Seal(nil, streamNonce(ew.prefix, ew.counter, last), ew.buffer, nil)
+	ew.counter++
+	ew.buffer = ew.buffer[:0]
+
+	_, err := ew.writer.Write(sealed)
+	return err
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	if ew.closed {
+		return 0, errors.New("write to closed encrypt writer")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		if len(ew.buffer) == StreamSegmentSize {
+			err := ew.seal(false)
+			if err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(ew.buffer[len(ew.buffer):StreamSegmentSize], p)
+		ew.buffer = ew.buffer[:len(ew.buffer)+n]
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+func (ew *encryptWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+
+	ew.closed = true
+	return ew.seal(true)
+}
+
+func NewDecryptReader(key []byte, reader io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReaderSize(reader, StreamSegmentSize+64)
+
+	magic, err := buffered.Peek(len(streamMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if !bytes.Equal(magic, streamMagic) {
+		cipherBuf, err := io.ReadAll(buffered)
+		if err != nil {
+			return nil, err
+		}
+
+		plainBuf, err := Decrypt(key, cipherBuf)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(plainBuf), nil
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(streamMagic)+streamPrefixSize)
+	if _, err = io.ReadFull(buffered, header); err != nil {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	return &decryptReader{
+		gcm:     gcm,
+		reader:  buffered,
+		prefix:  header[len(streamMagic):],
+		segment: make([]byte, StreamSegmentSize+gcm.Overhead()),
+	}, nil
+}
+
+func (dr *decryptReader) next() error {
+	n, err := io.ReadFull(dr.reader, dr.segment)
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	last := n < len(dr.segment)
+	if !last {
+		_, peekErr := dr.reader.Peek(1)
+		last = errors.Is(peekErr, io.EOF)
+	}
+
+	plain, err := dr.gcm.Open(dr.segment[:0:0], streamNonce(dr.prefix, dr.counter, last), dr.segment[:n], nil)
+	if err != nil {
+		if dr.counter == 0 {
+			return fmt.Errorf("key mismatch: wrong key used for decryption or ciphertext corrupt")
+		}
+		return fmt.Errorf("ciphertext segment %d is corrupt or truncated", dr.counter)
+	}
+
+	dr.counter++
+	dr.plain = plain
+	dr.done = last
+	return nil
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.plain) == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+
+		err := dr.next()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	return n, nil
+}
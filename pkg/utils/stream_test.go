@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+var streamKey = bytes.Repeat([]byte{0x42}, 32)
+
+func encryptStream(t *testing.T, key []byte, plain []byte) []byte {
+	var cipherBuf bytes.Buffer
+	writer, err := NewEncryptWriter(key, &cipherBuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for offset := 0; offset < len(plain); offset += 1000 {
+		_, err = writer.Write(plain[offset:min(offset+1000, len(plain))])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cipherBuf.Bytes()
+}
+
+func decryptStream(key []byte, cipherBuf []byte) ([]byte, error) {
+	reader, err := NewDecryptReader(key, bytes.NewReader(cipherBuf))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func segments(cipherBuf []byte) (header []byte, sealed [][]byte) {
+	header = cipherBuf[:len(streamMagic)+streamPrefixSize]
+	rest := cipherBuf[len(header):]
+	for len(rest) > 0 {
+		n := min(StreamSegmentSize+16, len(rest))
+		sealed = append(sealed, rest[:n])
+		rest = rest[n:]
+	}
+	return header, sealed
+}
+
+func join(header []byte, sealed ...[]byte) []byte {
+	return bytes.Join(append([][]byte{header}, sealed...), nil)
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, StreamSegmentSize - 1, StreamSegmentSize, StreamSegmentSize + 1, 3*StreamSegmentSize + 12345} {
+		plain := bytes.Repeat([]byte("stream"), size/6+1)[:size]
+
+		cipherBuf := encryptStream(t, streamKey, plain)
+		if !IsStream(cipherBuf) {
+			t.Fatalf("%d bytes: ciphertext has no stream header", size)
+		}
+
+		decrypted, err := decryptStream(streamKey, cipherBuf)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if !bytes.Equal(decrypted, plain) {
+			t.Fatalf("%d bytes: decrypted content differs", size)
+		}
+
+		decrypted, err = Decrypt(streamKey, cipherBuf)
+		if err != nil || !bytes.Equal(decrypted, plain) {
+			t.Fatalf("%d bytes: Decrypt failed on a stream: %v", size, err)
+		}
+	}
+}
+
+func TestStreamRejectsTampering(t *testing.T) {
+	plain := bytes.Repeat([]byte("segment"), 4*StreamSegmentSize/7)
+	cipherBuf := encryptStream(t, streamKey, plain)
+	header, sealed := segments(cipherBuf)
+	if len(sealed) != 4 {
+		t.Fatalf("expected 4 segments, got %d", len(sealed))
+	}
+
+	flipped := append([]byte{}, cipherBuf...)
+	flipped[len(header)+StreamSegmentSize+100] ^= 1
+
+	for name, tampered := range map[string][]byte{
+		"truncated at a segment boundary":       join(header, sealed[:3]...),
+		"truncated inside a segment":            cipherBuf[:len(cipherBuf)-10],
+		"truncated to the header":               header,
+		"segments reordered":                    join(header, sealed[0], sealed[2], sealed[1], sealed[3]),
+		"final segment moved":                   join(header, sealed[0], sealed[1], sealed[3], sealed[2]),
+		"segment dropped":                       join(header, sealed[0], sealed[2], sealed[3]),
+		"segment duplicated":                    join(header, sealed[0], sealed[1], sealed[1], sealed[2], sealed[3]),
+		"bit flipped":                           flipped,
+		"data appended after the final segment": join(header, sealed[0], sealed[1], sealed[2], sealed[3], sealed[0]),
+	} {
+		_, err := decryptStream(streamKey, tampered)
+		if err == nil {
+			t.Fatalf("%s: tampered stream was accepted", name)
+		}
+	}
+}
+
+func TestStreamRejectsSpliceFromOtherStream(t *testing.T) {
+	plain := bytes.Repeat([]byte("segment"), 2*StreamSegmentSize/7)
+	first := encryptStream(t, streamKey, plain)
+	second := encryptStream(t, streamKey, plain)
+
+	header, sealed := segments(first)
+	_, other := segments(second)
+
+	_, err := decryptStream(streamKey, join(header, sealed[0], other[1]))
+	if err == nil {
+		t.Fatal("segment from another stream was accepted")
+	}
+}
+
+func TestStreamWrongKey(t *testing.T) {
+	cipherBuf := encryptStream(t, streamKey, []byte("archive"))
+
+	_, err := decryptStream(bytes.Repeat([]byte{0x24}, 32), cipherBuf)
+	if err == nil || !strings.Contains(err.Error(), "key mismatch") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestStreamWriteAfterClose(t *testing.T) {
+	writer, err := NewEncryptWriter(streamKey, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = writer.Write([]byte("late"))
+	if err == nil {
+		t.Fatal("write after close was accepted")
+	}
+}